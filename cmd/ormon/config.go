@@ -7,11 +7,13 @@ import (
 
 	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
 	"github.com/bitsbeats/openshift-route-monitor/internal/monitor"
+	"github.com/bitsbeats/openshift-route-monitor/internal/scheduler"
 )
 
 type config struct {
-	Targets []kube.Config  `yaml:"targets"`
-	Monitor monitor.Config `yaml:"monitor"`
+	Targets   []kube.Config    `yaml:"targets"`
+	Monitor   monitor.Config   `yaml:"monitor"`
+	Scheduler scheduler.Config `yaml:"scheduler"`
 }
 
 // loadConfig loads the configuration
@@ -10,6 +10,7 @@ import (
 
 	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
 	"github.com/bitsbeats/openshift-route-monitor/internal/monitor"
+	"github.com/bitsbeats/openshift-route-monitor/internal/scheduler"
 )
 
 func main() {
@@ -35,8 +36,16 @@ func main() {
 	go mw.Watch(ctx)
 	logrus.Infoln("started watchers")
 
+	// start scheduler
+	scheduler, err := scheduler.New(config.Scheduler, mw)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	go scheduler.Run(ctx)
+	logrus.Infoln("started scheduler")
+
 	// start monitor
-	monitor, err := monitor.New(config.Monitor, mw)
+	monitor, err := monitor.New(config.Monitor, mw, scheduler)
 	if err != nil {
 		logrus.Fatal(err)
 	}
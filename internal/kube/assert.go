@@ -0,0 +1,166 @@
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// HeaderAssertion checks a response header for presence, an exact value
+	// or a regex
+	HeaderAssertion struct {
+		Name  string
+		Value string
+		Regex *regexp.Regexp
+		Mode  string
+	}
+
+	// BodyAssertion is a named regex the response body must or must not
+	// match
+	BodyAssertion struct {
+		Name   string
+		Regex  *regexp.Regexp
+		Negate bool
+	}
+
+	// AssertionResult is the result of a single named assertion
+	AssertionResult struct {
+		Name   string
+		Failed bool
+	}
+)
+
+// parseHeaderAssertions parses newline seperated header assertions:
+// `Name` checks for presence, `Name: value` for an exact value and
+// `Name ~ regex` matches a regex. Invalid lines are skipped.
+func (r *Route) parseHeaderAssertions(a string) (assertions []HeaderAssertion) {
+	for _, line := range strings.Split(a, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.IndexAny(line, ":~")
+		if i < 0 {
+			assertions = append(assertions, HeaderAssertion{Name: line, Mode: "present"})
+			continue
+		}
+		ha := HeaderAssertion{
+			Name:  strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+			Mode:  "exact",
+		}
+		if ha.Name == "" {
+			logrus.Warnf("invalid header assertion %q %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		if line[i] == '~' {
+			re, err := regexp.Compile(ha.Value)
+			if err != nil {
+				logrus.Warnf("invalid header assertion %q %s %s %s/%s", line, err, r.ClusterName, r.Namespace, r.Name)
+				continue
+			}
+			ha.Regex = re
+			ha.Mode = "regex"
+		}
+		assertions = append(assertions, ha)
+	}
+	return
+}
+
+// check evaluates the assertion against the response headers
+func (ha HeaderAssertion) check(header http.Header) error {
+	values, ok := header[http.CanonicalHeaderKey(ha.Name)]
+	if !ok {
+		return fmt.Errorf("header %s missing", ha.Name)
+	}
+	for _, v := range values {
+		switch ha.Mode {
+		case "present":
+			return nil
+		case "exact":
+			if v == ha.Value {
+				return nil
+			}
+		case "regex":
+			if ha.Regex.MatchString(v) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("header %s does not match %q", ha.Name, ha.Value)
+}
+
+// assertHeaders evaluates all header assertions, multiple assertions for the
+// same header are combined into a single result
+func (pi *ProbeInfo) assertHeaders(header http.Header) (results []AssertionResult) {
+	index := map[string]int{}
+	for _, ha := range pi.HeaderAssertions {
+		err := ha.check(header)
+		if err != nil {
+			logrus.Errorf("%s %s %s", err, pi.Cluster, pi.URL())
+		}
+		name := http.CanonicalHeaderKey(ha.Name)
+		if i, ok := index[name]; ok {
+			results[i].Failed = results[i].Failed || err != nil
+			continue
+		}
+		index[name] = len(results)
+		results = append(results, AssertionResult{Name: name, Failed: err != nil})
+	}
+	return
+}
+
+// parseBodyAssertions parses newline seperated named body assertions:
+// `name ~ regex` must match and `name !~ regex` must not match the body.
+// Invalid lines and duplicate names are skipped.
+func (r *Route) parseBodyAssertions(a string) (assertions []BodyAssertion) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(a, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "~")
+		if i < 0 {
+			logrus.Warnf("invalid body assertion %q %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		ba := BodyAssertion{Name: line[:i]}
+		if strings.HasSuffix(ba.Name, "!") {
+			ba.Name = strings.TrimSuffix(ba.Name, "!")
+			ba.Negate = true
+		}
+		ba.Name = strings.TrimSpace(ba.Name)
+		if ba.Name == "" || seen[ba.Name] {
+			logrus.Warnf("invalid body assertion %q empty or duplicate name %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		re, err := regexp.Compile(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			logrus.Warnf("invalid body assertion %q %s %s %s/%s", line, err, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		ba.Regex = re
+		seen[ba.Name] = true
+		assertions = append(assertions, ba)
+	}
+	return
+}
+
+// assertBody evaluates all named body assertions
+func (pi *ProbeInfo) assertBody(body []byte) (results []AssertionResult) {
+	for _, ba := range pi.BodyAssertions {
+		failed := ba.Regex.Match(body) == ba.Negate
+		if failed && ba.Negate {
+			logrus.Errorf("body assertion %s matches %s %s", ba.Name, pi.Cluster, pi.URL())
+		} else if failed {
+			logrus.Errorf("body assertion %s does not match %s %s", ba.Name, pi.Cluster, pi.URL())
+		}
+		results = append(results, AssertionResult{Name: ba.Name, Failed: failed})
+	}
+	return
+}
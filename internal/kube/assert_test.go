@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"net/http"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+func TestParseHeaderAssertions(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		line  string
+		valid bool
+		name  string
+		mode  string
+		value string
+	}{
+		{"X-Request-Id", true, "X-Request-Id", "present", ""},
+		{"Content-Type: application/json", true, "Content-Type", "exact", "application/json"},
+		{"Location: https://example.com/login", true, "Location", "exact", "https://example.com/login"},
+		{"Server ~ ^nginx/1\\.", true, "Server", "regex", "^nginx/1\\."},
+		{"Server ~ a:b", true, "Server", "regex", "a:b"},
+		{": value", false, "", "", ""},
+		{"~ regex", false, "", "", ""},
+		{"Server ~ (", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		assertions := r.parseHeaderAssertions(tt.line)
+		if !tt.valid {
+			if len(assertions) != 0 {
+				t.Errorf("%q: got %+v, want rejected", tt.line, assertions)
+			}
+			continue
+		}
+		if len(assertions) != 1 {
+			t.Errorf("%q: got %d assertions, want 1", tt.line, len(assertions))
+			continue
+		}
+		ha := assertions[0]
+		if ha.Name != tt.name || ha.Mode != tt.mode || ha.Value != tt.value {
+			t.Errorf("%q: got %q %q %q, want %q %q %q", tt.line, ha.Name, ha.Mode, ha.Value, tt.name, tt.mode, tt.value)
+		}
+	}
+}
+
+func TestAssertHeaders(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Add("Cache-Control", "no-cache")
+	header.Add("Cache-Control", "no-store")
+	tests := []struct {
+		assertions string
+		failed     map[string]bool
+	}{
+		{"content-type", map[string]bool{"Content-Type": false}},
+		{"Content-Type: application/json", map[string]bool{"Content-Type": false}},
+		{"Content-Type: text/html", map[string]bool{"Content-Type": true}},
+		{"Content-Type ~ ^application/", map[string]bool{"Content-Type": false}},
+		{"Cache-Control: no-store", map[string]bool{"Cache-Control": false}},
+		{"X-Missing", map[string]bool{"X-Missing": true}},
+		// assertions for the same header are combined
+		{"Content-Type\nContent-Type: text/html", map[string]bool{"Content-Type": true}},
+		{"Content-Type\ncontent-type ~ json", map[string]bool{"Content-Type": false}},
+	}
+	for _, tt := range tests {
+		pi := &ProbeInfo{HeaderAssertions: r.parseHeaderAssertions(tt.assertions)}
+		results := pi.assertHeaders(header)
+		if len(results) != len(tt.failed) {
+			t.Errorf("%q: got %d results, want %d", tt.assertions, len(results), len(tt.failed))
+			continue
+		}
+		for _, result := range results {
+			if failed, ok := tt.failed[result.Name]; !ok || failed != result.Failed {
+				t.Errorf("%q: got %s failed %t, want %t", tt.assertions, result.Name, result.Failed, failed)
+			}
+		}
+	}
+}
+
+func TestParseBodyAssertions(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		line   string
+		valid  bool
+		name   string
+		negate bool
+	}{
+		{`login form ~ <form[^>]+id="login"`, true, "login form", false},
+		{"no stack trace !~ Exception in", true, "no stack trace", true},
+		{"tilde ~ a~b", true, "tilde", false},
+		{"no operator", false, "", false},
+		{"~ regex", false, "", false},
+		{"!~ regex", false, "", false},
+		{"broken ~ (", false, "", false},
+	}
+	for _, tt := range tests {
+		assertions := r.parseBodyAssertions(tt.line)
+		if !tt.valid {
+			if len(assertions) != 0 {
+				t.Errorf("%q: got %+v, want rejected", tt.line, assertions)
+			}
+			continue
+		}
+		if len(assertions) != 1 {
+			t.Errorf("%q: got %d assertions, want 1", tt.line, len(assertions))
+			continue
+		}
+		ba := assertions[0]
+		if ba.Name != tt.name || ba.Negate != tt.negate {
+			t.Errorf("%q: got %q negate %t, want %q negate %t", tt.line, ba.Name, ba.Negate, tt.name, tt.negate)
+		}
+	}
+}
+
+func TestParseBodyAssertionsDuplicates(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	assertions := r.parseBodyAssertions("title ~ <title>\ntitle !~ error\nfooter ~ </footer>")
+	if len(assertions) != 2 {
+		t.Fatalf("got %d assertions, want 2", len(assertions))
+	}
+	if assertions[0].Name != "title" || assertions[0].Negate {
+		t.Errorf("got %q negate %t, want the first title assertion", assertions[0].Name, assertions[0].Negate)
+	}
+}
+
+func TestAssertBody(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	pi := &ProbeInfo{BodyAssertions: r.parseBodyAssertions(
+		"title ~ <title>Shop</title>\nlogin ~ id=\"login\"\nno trace !~ Exception in\nno error !~ error",
+	)}
+	body := []byte(`<title>Shop</title><p>error</p>`)
+	want := map[string]bool{"title": false, "login": true, "no trace": false, "no error": true}
+	results := pi.assertBody(body)
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for _, result := range results {
+		if result.Failed != want[result.Name] {
+			t.Errorf("%s: got failed %t, want %t", result.Name, result.Failed, want[result.Name])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+// CertificateAnalysis is the result of the static analysis of the
+// certificates in the Route's spec.tls
+type CertificateAnalysis struct {
+	// Expires holds the earliest expiry per spec.tls field
+	Expires map[string]time.Time
+
+	// Custom is true if spec.tls.certificate is set, the other checks are
+	// only performed for custom certificates
+	Custom      bool
+	ParseErr    bool
+	KeyMismatch bool
+	ChainValid  bool
+	HostCovered bool
+}
+
+// AnalyzeCertificates parses the PEMs of the Route's spec.tls without
+// probing the Route, returns nil for Routes without tls
+func (r *Route) AnalyzeCertificates() (ca *CertificateAnalysis) {
+	if r.Spec.TLS == nil {
+		return nil
+	}
+	ca = &CertificateAnalysis{Expires: map[string]time.Time{}}
+	parsed := map[string][]*x509.Certificate{}
+	for field, data := range map[string]string{
+		"certificate":                r.Spec.TLS.Certificate,
+		"ca_certificate":             r.Spec.TLS.CACertificate,
+		"destination_ca_certificate": r.Spec.TLS.DestinationCACertificate,
+	} {
+		if data == "" {
+			continue
+		}
+		certs, err := parseCertificates(data)
+		if err != nil {
+			ca.ParseErr = true
+			continue
+		}
+		parsed[field] = certs
+		ca.Expires[field] = expiresFirst(certs)
+	}
+
+	certs, ok := parsed["certificate"]
+	if !ok {
+		return
+	}
+	ca.Custom = true
+	if r.Spec.TLS.Key != "" {
+		_, err := tls.X509KeyPair([]byte(r.Spec.TLS.Certificate), []byte(r.Spec.TLS.Key))
+		ca.KeyMismatch = err != nil
+	}
+	intermediates := append(certs[1:], parsed["ca_certificate"]...)
+	ca.ChainValid = r.verifyChain(certs[0], intermediates, "") == nil
+	ca.HostCovered = certs[0].VerifyHostname(r.Spec.Host) == nil
+	return
+}
+
+// compareServedCertificate compares the leaf served by the router with
+// spec.tls.certificate of edge and reencrypt Routes. If the target's default
+// router certificate is known, a served default certificate is detected by
+// comparing with it. Otherwise a differing wildcard certificate most likely
+// means the router rejected the custom certificate, unless the custom
+// certificate is a wildcard covering the host itself.
+func (r *Route) compareServedCertificate(served *x509.Certificate) (mismatch, defaultCert bool) {
+	if served == nil || r.Spec.TLS == nil || r.Spec.TLS.Certificate == "" {
+		return false, false
+	}
+	switch r.Spec.TLS.Termination {
+	case routev1.TLSTerminationEdge, routev1.TLSTerminationReencrypt:
+	default:
+		return false, false
+	}
+	certs, err := parseCertificates(r.Spec.TLS.Certificate)
+	if err != nil {
+		return false, false
+	}
+	if bytes.Equal(certs[0].Raw, served.Raw) {
+		return false, false
+	}
+	if r.watcher != nil && len(r.watcher.defaultRouterCerts) > 0 {
+		for _, cert := range r.watcher.defaultRouterCerts {
+			if bytes.Equal(cert.Raw, served.Raw) {
+				return true, true
+			}
+		}
+		return true, false
+	}
+	if isWildcard(certs[0]) && certs[0].VerifyHostname(r.Spec.Host) == nil {
+		return true, false
+	}
+	return true, isWildcard(served)
+}
+
+// isWildcard reports whether cert has a wildcard SAN
+func isWildcard(cert *x509.Certificate) bool {
+	for _, name := range cert.DNSNames {
+		if strings.HasPrefix(name, "*.") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDefaultRouterCerts loads the PEM encoded default certificates of the
+// routers from disk, returns nil if none are configured
+func loadDefaultRouterCerts(file string) ([]*x509.Certificate, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return certs, nil
+}
+
+// parseCertificates parses all certificates of a PEM bundle
+func parseCertificates(data string) (certs []*x509.Certificate, err error) {
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,93 @@
+package kube
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+// newCertificate creates a self-signed certificate for names and returns it
+// parsed and PEM encoded
+func newCertificate(t *testing.T, names ...string) (*x509.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestCompareServedCertificate(t *testing.T) {
+	host := "shop.apps.example.com"
+	custom, customPEM := newCertificate(t, host)
+	customWildcard, customWildcardPEM := newCertificate(t, "*.apps.example.com")
+	_, otherWildcardPEM := newCertificate(t, "*.other.example.com")
+	routerDefault, _ := newCertificate(t, "*.apps.example.com")
+	foreign, _ := newCertificate(t, "shop.apps.example.com")
+	foreignWildcard, _ := newCertificate(t, "*.apps.example.com")
+
+	tests := []struct {
+		name          string
+		termination   routev1.TLSTerminationType
+		certificate   string
+		defaultCerts  []*x509.Certificate
+		served        *x509.Certificate
+		mismatch      bool
+		defaultServed bool
+	}{
+		{"custom served", routev1.TLSTerminationEdge, customPEM, nil, custom, false, false},
+		{"custom wildcard served", routev1.TLSTerminationEdge, customWildcardPEM, nil, customWildcard, false, false},
+		{"configured default served", routev1.TLSTerminationEdge, customPEM, []*x509.Certificate{routerDefault}, routerDefault, true, true},
+		{"configured default, foreign served", routev1.TLSTerminationEdge, customPEM, []*x509.Certificate{routerDefault}, foreign, true, false},
+		{"configured default, foreign wildcard served", routev1.TLSTerminationEdge, customPEM, []*x509.Certificate{routerDefault}, foreignWildcard, true, false},
+		{"wildcard served for custom", routev1.TLSTerminationEdge, customPEM, nil, routerDefault, true, true},
+		{"wildcard served for custom reencrypt", routev1.TLSTerminationReencrypt, customPEM, nil, routerDefault, true, true},
+		{"foreign served for custom", routev1.TLSTerminationEdge, customPEM, nil, foreign, true, false},
+		{"wildcard served for covering custom wildcard", routev1.TLSTerminationEdge, customWildcardPEM, nil, routerDefault, true, false},
+		{"wildcard served for other custom wildcard", routev1.TLSTerminationEdge, otherWildcardPEM, nil, routerDefault, true, true},
+		{"passthrough", routev1.TLSTerminationPassthrough, customPEM, nil, routerDefault, false, false},
+		{"no custom certificate", routev1.TLSTerminationEdge, "", nil, routerDefault, false, false},
+		{"invalid custom certificate", routev1.TLSTerminationEdge, "invalid", nil, routerDefault, false, false},
+		{"nothing served", routev1.TLSTerminationEdge, customPEM, nil, nil, false, false},
+	}
+	for _, tt := range tests {
+		r := &Route{
+			Route: &routev1.Route{Spec: routev1.RouteSpec{
+				Host: host,
+				TLS:  &routev1.TLSConfig{Termination: tt.termination, Certificate: tt.certificate},
+			}},
+			watcher: &Watcher{defaultRouterCerts: tt.defaultCerts},
+		}
+		mismatch, defaultServed := r.compareServedCertificate(tt.served)
+		if mismatch != tt.mismatch || defaultServed != tt.defaultServed {
+			t.Errorf("%s: got mismatch %t default %t, want %t %t", tt.name, mismatch, defaultServed, tt.mismatch, tt.defaultServed)
+		}
+	}
+}
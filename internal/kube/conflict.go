@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"sort"
+)
+
+type (
+	// Conflict is a host and path combination defined by multiple Routes
+	Conflict struct {
+		Host   string     `json:"host"`
+		Path   string     `json:"path"`
+		Routes []RouteRef `json:"routes"`
+	}
+
+	// RouteRef references a Route in a cluster
+	RouteRef struct {
+		Cluster   string `json:"cluster"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		UID       string `json:"uid"`
+	}
+)
+
+// FindConflicts detects duplicate host and path combinations across
+// namespaces and clusters
+func FindConflicts(routes []*Route) (conflicts []Conflict) {
+	type key struct{ host, path string }
+	byKey := map[key][]RouteRef{}
+	for _, r := range routes {
+		k := key{r.Spec.Host, r.Spec.Path}
+		byKey[k] = append(byKey[k], RouteRef{
+			Cluster:   r.ClusterName,
+			Namespace: r.Namespace,
+			Name:      r.Name,
+			UID:       string(r.GetUID()),
+		})
+	}
+	conflicts = []Conflict{}
+	for k, refs := range byKey {
+		if len(refs) < 2 {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{Host: k.host, Path: k.path, Routes: refs})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Host != conflicts[j].Host {
+			return conflicts[i].Host < conflicts[j].Host
+		}
+		return conflicts[i].Path < conflicts[j].Path
+	})
+	return
+}
+
+// Conflicts detects duplicate host and path combinations of all Routes
+func (mw *MultiWatcher) Conflicts() []Conflict {
+	return FindConflicts(mw.List())
+}
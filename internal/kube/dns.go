@@ -0,0 +1,462 @@
+package kube
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsMaxCNAMEDepth = 16
+
+	// dnsQueryTimeout limits a single query to a single server
+	dnsQueryTimeout = 2 * time.Second
+
+	// dnsAttempts is the number of times all servers are tried
+	dnsAttempts = 2
+
+	// dnsVerifyTimeout limits the whole dns verification of a host
+	dnsVerifyTimeout = 5 * time.Second
+
+	// dnsServerBackoff is how long a failed server is tried last
+	dnsServerBackoff = 30 * time.Second
+)
+
+type (
+	// DNSConfig configures the dns servers used to resolve a target's Routes
+	DNSConfig struct {
+		Servers  []string `yaml:"servers"`
+		Protocol string   `yaml:"protocol"`
+	}
+
+	// dnsClient resolves hostnames using a fixed set of dns servers, the
+	// system servers are used if none are configured
+	dnsClient struct {
+		servers  []string
+		system   bool
+		protocol string
+		dialer   *net.Dialer
+
+		mu     sync.Mutex
+		failed map[string]time.Time
+	}
+
+	// dnsConn reports the health of its server to the dnsClient and limits
+	// every deadline to dnsQueryTimeout
+	dnsConn struct {
+		net.Conn
+		client *dnsClient
+		server string
+	}
+
+	// dnsPacketConn is a dnsConn for udp, the go resolver only uses packet
+	// framing for net.PacketConns
+	dnsPacketConn struct {
+		*dnsConn
+		pc net.PacketConn
+	}
+)
+
+// newDNSClient creates a dnsClient from DNSConfig, supported protocols are
+// udp, tcp and tls (dns over tls)
+func newDNSClient(c DNSConfig) (*dnsClient, error) {
+	if c.Protocol == "" {
+		c.Protocol = "udp"
+	}
+	port := "53"
+	switch c.Protocol {
+	case "udp", "tcp":
+	case "tls":
+		port = "853"
+	default:
+		return nil, fmt.Errorf("unknown dns protocol %q", c.Protocol)
+	}
+	servers := []string{}
+	for _, server := range c.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, port)
+		}
+		servers = append(servers, server)
+	}
+	system := len(servers) == 0
+	if system {
+		servers = systemDNSServers()
+	}
+	return &dnsClient{
+		servers:  servers,
+		system:   system,
+		protocol: c.Protocol,
+		dialer:   &net.Dialer{Timeout: 5 * time.Second},
+		failed:   map[string]time.Time{},
+	}, nil
+}
+
+// systemDNSServers reads the nameservers from /etc/resolv.conf
+func systemDNSServers() (servers []string) {
+	f, err := os.Open("/etc/resolv.conf")
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:53"}
+	}
+	return
+}
+
+// resolver returns a net.Resolver using the servers of c. The go resolver
+// passes the address of a nameserver from /etc/resolv.conf to Dial, which is
+// replaced by the healthiest configured server. Each retry of the go
+// resolver dials again and thereby fails over to the next server.
+func (c *dnsClient) resolver() *net.Resolver {
+	if c.system {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return c.dial(ctx, network)
+		},
+	}
+}
+
+// dial opens a connection to the healthiest reachable server. The network
+// is only honored for udp, which falls back to tcp for truncated responses.
+func (c *dnsClient) dial(ctx context.Context, network string) (conn net.Conn, err error) {
+	for _, server := range c.ordered() {
+		conn, err = c.dialServer(ctx, network, server)
+		if err != nil {
+			c.report(server, err)
+			continue
+		}
+		dc := &dnsConn{Conn: conn, client: c, server: server}
+		if pc, ok := conn.(net.PacketConn); ok {
+			return &dnsPacketConn{dnsConn: dc, pc: pc}, nil
+		}
+		return dc, nil
+	}
+	return nil, err
+}
+
+// ordered returns the servers in configured order, servers that failed
+// within dnsServerBackoff last
+func (c *dnsClient) ordered() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	healthy, failed := []string{}, []string{}
+	for _, server := range c.servers {
+		if now.Before(c.failed[server]) {
+			failed = append(failed, server)
+			continue
+		}
+		healthy = append(healthy, server)
+	}
+	return append(healthy, failed...)
+}
+
+// report records the result of a query to server
+func (c *dnsClient) report(server string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.failed[server] = time.Now().Add(dnsServerBackoff)
+		return
+	}
+	delete(c.failed, server)
+}
+
+// Read reports the health of the server
+func (dc *dnsConn) Read(b []byte) (n int, err error) {
+	n, err = dc.Conn.Read(b)
+	dc.client.report(dc.server, err)
+	return
+}
+
+// SetDeadline limits t to dnsQueryTimeout
+func (dc *dnsConn) SetDeadline(t time.Time) error {
+	if max := time.Now().Add(dnsQueryTimeout); t.IsZero() || t.After(max) {
+		t = max
+	}
+	return dc.Conn.SetDeadline(t)
+}
+
+// ReadFrom implements net.PacketConn
+func (dpc *dnsPacketConn) ReadFrom(b []byte) (n int, addr net.Addr, err error) {
+	n, addr, err = dpc.pc.ReadFrom(b)
+	dpc.client.report(dpc.server, err)
+	return
+}
+
+// WriteTo implements net.PacketConn
+func (dpc *dnsPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return dpc.pc.WriteTo(b, addr)
+}
+
+// dialServer opens a connection to server using the client's protocol
+func (c *dnsClient) dialServer(ctx context.Context, network, server string) (net.Conn, error) {
+	switch c.protocol {
+	case "tcp":
+		return c.dialer.DialContext(ctx, "tcp", server)
+	case "tls":
+		host, _, _ := net.SplitHostPort(server)
+		d := tls.Dialer{NetDialer: c.dialer, Config: &tls.Config{ServerName: host}}
+		return d.DialContext(ctx, "tcp", server)
+	}
+	return c.dialer.DialContext(ctx, network, server)
+}
+
+// exchange sends a single query and returns the response. Every server is
+// tried dnsAttempts times with a timeout of dnsQueryTimeout per query.
+func (c *dnsClient) exchange(ctx context.Context, name string, qtype dnsmessage.Type) (resp *dnsmessage.Message, err error) {
+	qname, err := dnsmessage.NewName(fqdn(name))
+	if err != nil {
+		return nil, err
+	}
+	question := dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}
+	err = errors.New("no dns servers")
+	for attempt := 0; attempt < dnsAttempts; attempt++ {
+		for _, server := range c.ordered() {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			resp, err = c.query(ctx, server, question)
+			c.report(server, err)
+			if err == nil {
+				return resp, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+// query sends question to a single server
+func (c *dnsClient) query(ctx context.Context, server string, question dnsmessage.Question) (*dnsmessage.Message, error) {
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{question},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+	conn, err := c.dialServer(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	var buf []byte
+	if _, ok := conn.(net.PacketConn); ok {
+		_, err = conn.Write(packed)
+		if err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	} else {
+		// stream connections prefix messages with their length
+		_, err = conn.Write(append([]byte{byte(len(packed) >> 8), byte(len(packed))}, packed...))
+		if err != nil {
+			return nil, err
+		}
+		length := make([]byte, 2)
+		_, err = io.ReadFull(conn, length)
+		if err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(length))
+		_, err = io.ReadFull(conn, buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &dnsmessage.Message{}
+	err = resp.Unpack(buf)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != query.ID {
+		return nil, errors.New("dns response id mismatch")
+	}
+	return resp, nil
+}
+
+// cnameChain follows the CNAME records of host
+func (c *dnsClient) cnameChain(ctx context.Context, host string) (chain []string, err error) {
+	name := host
+	for len(chain) < dnsMaxCNAMEDepth {
+		resp, err := c.exchange(ctx, name, dnsmessage.TypeCNAME)
+		if err != nil {
+			return chain, err
+		}
+		target := ""
+		for _, a := range resp.Answers {
+			cname, ok := a.Body.(*dnsmessage.CNAMEResource)
+			if ok && strings.EqualFold(a.Header.Name.String(), fqdn(name)) {
+				target = cname.CNAME.String()
+			}
+		}
+		if target == "" {
+			break
+		}
+		chain = append(chain, strings.TrimSuffix(target, "."))
+		name = target
+	}
+	return chain, nil
+}
+
+// verifyDNS checks that host points to the target's expected cidrs or, if
+// none are configured, to the routers from status.ingress, and returns the
+// length of its CNAME chain. It runs once per host alongside the main probes
+// within dnsVerifyTimeout. Failing resolvers are no mismatch, neither are
+// unknown hosts of proxied Routes as the proxy may resolve them.
+func (r *Route) verifyDNS(ctx context.Context, host string, proxied bool) (cnameDepth int, mismatch bool) {
+	host = hostname(host)
+	if net.ParseIP(host) != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, dnsVerifyTimeout)
+	defer cancel()
+	c := r.dnsClient()
+	chain, err := c.cnameChain(ctx, host)
+	if err != nil {
+		logrus.Warnf("%s %s %s", err, r.ClusterName, host)
+	}
+	cnameDepth = len(chain)
+
+	expectedCIDRs := []*net.IPNet{}
+	if r.watcher != nil {
+		expectedCIDRs = r.watcher.expectedCIDRs
+	}
+	canonicalHostnames := []string{}
+	for _, router := range r.admittedRouters() {
+		canonicalHostnames = append(canonicalHostnames, router.CanonicalHostname)
+	}
+	if len(expectedCIDRs) == 0 && len(canonicalHostnames) == 0 {
+		// nothing to verify against
+		return
+	}
+
+	addrs, err := c.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		switch {
+		case !errors.As(err, &dnsErr) || !dnsErr.IsNotFound:
+			// timeouts and server failures say nothing about the records
+			logrus.Warnf("%s %s %s", err, r.ClusterName, host)
+		case proxied:
+			logrus.Debugf("%s left to the proxy %s %s", err, r.ClusterName, host)
+		default:
+			mismatch = true
+			logrus.Errorf("%s %s %s", err, r.ClusterName, host)
+		}
+		return
+	}
+	expected := func(ip net.IP) bool {
+		for _, cidr := range expectedCIDRs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(expectedCIDRs) == 0 {
+		for _, cname := range chain {
+			for _, ch := range canonicalHostnames {
+				if strings.EqualFold(cname, ch) {
+					return
+				}
+			}
+		}
+		routerIPs := map[string]bool{}
+		for _, ch := range canonicalHostnames {
+			routerAddrs, err := c.resolver().LookupIPAddr(ctx, ch)
+			if err != nil {
+				logrus.Warnf("%s %s %s", err, r.ClusterName, host)
+				continue
+			}
+			for _, ra := range routerAddrs {
+				routerIPs[ra.IP.String()] = true
+			}
+		}
+		expected = func(ip net.IP) bool {
+			return routerIPs[ip.String()]
+		}
+	}
+	for _, addr := range addrs {
+		if !expected(addr.IP) {
+			mismatch = true
+			logrus.Errorf("%s resolves to unexpected address %s %s", host, addr.IP, r.ClusterName)
+			return
+		}
+	}
+	return
+}
+
+// dnsClient returns the dnsClient of the Route's target
+func (r *Route) dnsClient() *dnsClient {
+	if r.watcher == nil {
+		c, _ := newDNSClient(DNSConfig{})
+		return c
+	}
+	return r.watcher.dns
+}
+
+// parseCIDRs parses a list of cidrs
+func parseCIDRs(cidrs []string) (nets []*net.IPNet, err error) {
+	nets = []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return
+}
+
+// hostname strips the port from host
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// fqdn appends the root label to name
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
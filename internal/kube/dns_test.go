@@ -0,0 +1,238 @@
+package kube
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNSServer answers queries from records on a local udp socket, ip
+// values are answered as A records and names as CNAME records
+func fakeDNSServer(t *testing.T, records map[string]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := dnsmessage.Message{}
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			target, ok := records[strings.TrimSuffix(q.Name.String(), ".")]
+			ip := net.ParseIP(target).To4()
+			switch {
+			case ok && ip != nil && q.Type == dnsmessage.TypeA:
+				a := dnsmessage.AResource{}
+				copy(a.A[:], ip)
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+					Body:   &a,
+				}}
+			case ok && ip == nil:
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET},
+					Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(fqdn(target))},
+				}}
+			}
+			packed, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// silentDNSServer never answers
+func silentDNSServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String()
+}
+
+func TestCNAMEChain(t *testing.T) {
+	server := fakeDNSServer(t, map[string]string{
+		"app.example.com":    "router.example.com",
+		"router.example.com": "lb.example.net",
+	})
+	c, err := newDNSClient(DNSConfig{Servers: []string{server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.cnameChain(context.Background(), "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"router.example.com", "lb.example.net"}
+	if strings.Join(chain, ",") != strings.Join(want, ",") {
+		t.Errorf("got chain %v, want %v", chain, want)
+	}
+}
+
+func TestExchangeFailover(t *testing.T) {
+	server := fakeDNSServer(t, map[string]string{
+		"app.example.com":    "router.example.com",
+		"router.example.com": "lb.example.net",
+	})
+	c, err := newDNSClient(DNSConfig{Servers: []string{silentDNSServer(t), server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	chain, err := c.cnameChain(context.Background(), "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 {
+		t.Errorf("got chain %v, want two cnames", chain)
+	}
+	// only the first query waits for the silent server
+	if d := time.Since(start); d > dnsQueryTimeout+time.Second {
+		t.Errorf("failover took %s", d)
+	}
+}
+
+func TestResolverFailover(t *testing.T) {
+	server := fakeDNSServer(t, map[string]string{"app.example.com": "192.0.2.1"})
+	c, err := newDNSClient(DNSConfig{Servers: []string{silentDNSServer(t), server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	addrs, err := c.resolver().LookupIPAddr(ctx, "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].IP.String() != "192.0.2.1" {
+		t.Errorf("got %v, want 192.0.2.1", addrs)
+	}
+}
+
+func TestExchangeTimeout(t *testing.T) {
+	c, err := newDNSClient(DNSConfig{Servers: []string{silentDNSServer(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = c.exchange(ctx, "app.example.com", dnsmessage.TypeCNAME)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if d := time.Since(start); d > dnsAttempts*dnsQueryTimeout+time.Second {
+		t.Errorf("exchange took %s, want at most %s", d, dnsAttempts*dnsQueryTimeout)
+	}
+}
+
+func TestSkipIPVersion(t *testing.T) {
+	server := fakeDNSServer(t, map[string]string{"v4.example.com": "192.0.2.1"})
+	c, err := newDNSClient(DNSConfig{Servers: []string{server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Route{watcher: &Watcher{dns: c}}
+	tests := []struct {
+		host    string
+		version string
+		proxied bool
+		skip    bool
+	}{
+		{"v4.example.com", "4", false, false},
+		{"v4.example.com", "6", false, true},
+		{"v4.example.com", "6", true, true},
+		// hosts without addresses are probed once to report the failure or,
+		// through a proxy, to let the proxy resolve them
+		{"gone.example.com", "4", false, false},
+		{"gone.example.com", "6", false, true},
+		{"gone.example.com", "4", true, false},
+		{"gone.example.com", "6", true, true},
+	}
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+	for _, tt := range tests {
+		pi := &ProbeInfo{Host: tt.host, IPVersion: tt.version}
+		if tt.proxied {
+			pi.ProxyURL = proxyURL
+		}
+		if skip := r.skipIPVersion(context.Background(), pi); skip != tt.skip {
+			t.Errorf("%s over ipv%s: got skip %t, want %t", tt.host, tt.version, skip, tt.skip)
+		}
+	}
+}
+
+func TestVerifyDNS(t *testing.T) {
+	server := fakeDNSServer(t, map[string]string{
+		"app.example.com":   "192.0.2.1",
+		"other.example.com": "198.51.100.1",
+	})
+	c, err := newDNSClient(DNSConfig{Servers: []string{server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCIDRs, err := parseCIDRs([]string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Route{Route: &routev1.Route{}, watcher: &Watcher{dns: c, expectedCIDRs: expectedCIDRs}}
+	tests := []struct {
+		host     string
+		proxied  bool
+		mismatch bool
+	}{
+		{"app.example.com:443", false, false},
+		{"other.example.com", false, true},
+		{"gone.example.com", false, true},
+		// the proxy may resolve hosts unknown locally
+		{"gone.example.com", true, false},
+		{"other.example.com", true, true},
+		// addresses are not verified
+		{"192.0.2.1", false, false},
+	}
+	for _, tt := range tests {
+		if _, mismatch := r.verifyDNS(context.Background(), tt.host, tt.proxied); mismatch != tt.mismatch {
+			t.Errorf("%s proxied %t: got mismatch %t, want %t", tt.host, tt.proxied, mismatch, tt.mismatch)
+		}
+	}
+}
+
+func TestVerifyDNSResolverFailure(t *testing.T) {
+	c, err := newDNSClient(DNSConfig{Servers: []string{silentDNSServer(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCIDRs, err := parseCIDRs([]string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Route{Route: &routev1.Route{}, watcher: &Watcher{dns: c, expectedCIDRs: expectedCIDRs}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, mismatch := r.verifyDNS(ctx, "app.example.com", false); mismatch {
+		t.Error("resolver timeout reported as mismatch")
+	}
+}
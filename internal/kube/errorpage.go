@@ -0,0 +1,49 @@
+package kube
+
+import (
+	"regexp"
+)
+
+// defaultErrorPageSignatures match the error page served by the OpenShift
+// router if a Route has no ready endpoints
+var defaultErrorPageSignatures = []*regexp.Regexp{
+	regexp.MustCompile(`<h1>\s*Application is not available\s*</h1>`),
+	regexp.MustCompile(`The application is currently not serving requests at this endpoint`),
+}
+
+// parseErrorPageSignatures compiles the custom error page signatures of a
+// target
+func parseErrorPageSignatures(signatures []string) (res []*regexp.Regexp, err error) {
+	res = []*regexp.Regexp{}
+	for _, signature := range signatures {
+		re, err := regexp.Compile(signature)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return
+}
+
+// errorPageSignatures returns the default and the target's error page
+// signatures
+func (r *Route) errorPageSignatures() []*regexp.Regexp {
+	if r.watcher == nil {
+		return defaultErrorPageSignatures
+	}
+	return append(append([]*regexp.Regexp{}, defaultErrorPageSignatures...), r.watcher.errorPageSignatures...)
+}
+
+// isRouterErrorPage reports whether an error response was served by the
+// router instead of the application
+func (r *Route) isRouterErrorPage(statusCode int, body []byte) bool {
+	if statusCode < 400 {
+		return false
+	}
+	for _, re := range r.errorPageSignatures() {
+		if re.Match(body) {
+			return true
+		}
+	}
+	return false
+}
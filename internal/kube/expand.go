@@ -0,0 +1,46 @@
+package kube
+
+// expand returns one ProbeInfo per probed host, router and ip version
+func (pi *ProbeInfo) expand() (pis []*ProbeInfo) {
+	pis = []*ProbeInfo{pi}
+	if pi.Wildcard != "" {
+		pis = expandEach(pis, func(pi *ProbeInfo) (pis []*ProbeInfo) {
+			for _, host := range pi.WildcardHosts {
+				c := *pi
+				c.Host = host
+				pis = append(pis, &c)
+			}
+			return
+		})
+	}
+	if len(pi.Routers) > 0 {
+		pis = expandEach(pis, func(pi *ProbeInfo) (pis []*ProbeInfo) {
+			for _, router := range pi.Routers {
+				c := *pi
+				c.Router = router.Name
+				c.RouterAddress = router.CanonicalHostname
+				pis = append(pis, &c)
+			}
+			return
+		})
+	}
+	if pi.DualStack {
+		pis = expandEach(pis, func(pi *ProbeInfo) (pis []*ProbeInfo) {
+			for _, version := range []string{"4", "6"} {
+				c := *pi
+				c.IPVersion = version
+				pis = append(pis, &c)
+			}
+			return
+		})
+	}
+	return
+}
+
+// expandEach applies f to all pis and concatenates the results
+func expandEach(pis []*ProbeInfo, f func(*ProbeInfo) []*ProbeInfo) (expanded []*ProbeInfo) {
+	for _, pi := range pis {
+		expanded = append(expanded, f(pi)...)
+	}
+	return
+}
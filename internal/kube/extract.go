@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Extraction reads a numeric value from the response body, either by
+	// JSONPath or by regex
+	Extraction struct {
+		Key   string
+		Path  string
+		Regex *regexp.Regexp
+	}
+
+	// ExtractedValue is the numeric result of an Extraction
+	ExtractedValue struct {
+		Key   string
+		Value float64
+	}
+)
+
+// parseExtractions parses newline seperated extraction rules of the form
+// `key = {.jsonpath}` or `key ~ regex`. For regexes the first capture group
+// is used if present, the whole match otherwise. Invalid lines and
+// duplicate keys are skipped.
+func (r *Route) parseExtractions(a string) (extractions []Extraction) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(a, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.IndexAny(line, "=~")
+		if i < 1 {
+			logrus.Warnf("invalid extraction %q %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		e := Extraction{Key: strings.TrimSpace(line[:i])}
+		expr := strings.TrimSpace(line[i+1:])
+		if e.Key == "" || seen[e.Key] {
+			logrus.Warnf("invalid extraction %q empty or duplicate key %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		var err error
+		switch line[i] {
+		case '=':
+			var rest string
+			e.Path, rest, err = splitJSONPath(expr)
+			if err == nil && rest != "" {
+				err = fmt.Errorf("unexpected %q", rest)
+			}
+		case '~':
+			e.Regex, err = regexp.Compile(expr)
+		}
+		if err != nil {
+			logrus.Warnf("invalid extraction %q %s %s %s/%s", line, err, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		seen[e.Key] = true
+		extractions = append(extractions, e)
+	}
+	return
+}
+
+// value extracts the raw value from the body or the parsed json data
+func (e Extraction) value(body []byte, data interface{}) (string, error) {
+	if e.Regex != nil {
+		match := e.Regex.FindSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("extraction %s regex does not match", e.Key)
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+	v, err := jsonPathValue(e.Path, data)
+	if err != nil {
+		return "", err
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+	}
+	return jsonString(v), nil
+}
+
+// extract evaluates all extractions, values that can not be found or are
+// not numeric are skipped. JSONPath extractions are skipped if the body is
+// no valid json.
+func (pi *ProbeInfo) extract(body []byte, data interface{}, parseErr bool) (values []ExtractedValue) {
+	for _, e := range pi.Extractions {
+		if e.Path != "" && parseErr {
+			continue
+		}
+		raw, err := e.value(body, data)
+		if err != nil {
+			logrus.Errorf("%s %s %s", err, pi.Cluster, pi.URL())
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			logrus.Errorf("extraction %s %q is not a number %s %s", e.Key, raw, pi.Cluster, pi.URL())
+			continue
+		}
+		values = append(values, ExtractedValue{Key: e.Key, Value: f})
+	}
+	return
+}
@@ -0,0 +1,93 @@
+package kube
+
+import (
+	"encoding/json"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+func TestParseExtractions(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		line  string
+		valid bool
+		key   string
+		path  string
+		regex string
+	}{
+		{"queue_depth = {.queue.depth}", true, "queue_depth", "{.queue.depth}", ""},
+		{"build ~ build-([0-9]+)", true, "build", "", "build-([0-9]+)"},
+		{"build ~ id=([0-9]+)", true, "build", "", "id=([0-9]+)"},
+		{"= {.queue.depth}", false, "", "", ""},
+		{"queue_depth", false, "", "", ""},
+		{"queue_depth = .queue.depth", false, "", "", ""},
+		{"queue_depth = {.queue.depth} + 1", false, "", "", ""},
+		{"build ~ (", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		extractions := r.parseExtractions(tt.line)
+		if !tt.valid {
+			if len(extractions) != 0 {
+				t.Errorf("%q: got %+v, want rejected", tt.line, extractions)
+			}
+			continue
+		}
+		if len(extractions) != 1 {
+			t.Errorf("%q: got %d extractions, want 1", tt.line, len(extractions))
+			continue
+		}
+		e := extractions[0]
+		regex := ""
+		if e.Regex != nil {
+			regex = e.Regex.String()
+		}
+		if e.Key != tt.key || e.Path != tt.path || regex != tt.regex {
+			t.Errorf("%q: got %q %q %q, want %q %q %q", tt.line, e.Key, e.Path, regex, tt.key, tt.path, tt.regex)
+		}
+	}
+}
+
+func TestParseExtractionsDuplicates(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	extractions := r.parseExtractions("depth = {.depth}\ndepth ~ depth ([0-9]+)\nsize = {.size}")
+	if len(extractions) != 2 {
+		t.Fatalf("got %d extractions, want 2", len(extractions))
+	}
+	if extractions[0].Path != "{.depth}" {
+		t.Errorf("got %+v, want the first depth extraction", extractions[0])
+	}
+}
+
+func TestExtract(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	body := []byte(`{"depth": 42, "ratio": "0.5", "ready": true, "name": "shop", "log": "build-1234 took 3s"}`)
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatal(err)
+	}
+	pi := &ProbeInfo{Extractions: r.parseExtractions(`depth = {.depth}
+ratio = {.ratio}
+ready = {.ready}
+name = {.name}
+missing = {.missing}
+build ~ build-([0-9]+)
+took ~ [0-9]+s
+seconds ~ took ([0-9]+)s`)}
+	want := map[string]float64{"depth": 42, "ratio": 0.5, "ready": 1, "build": 1234, "seconds": 3}
+	values := pi.extract(body, data, false)
+	if len(values) != len(want) {
+		t.Errorf("got %+v, want %v", values, want)
+	}
+	for _, v := range values {
+		if w, ok := want[v.Key]; !ok || w != v.Value {
+			t.Errorf("%s: got %g, want %g", v.Key, v.Value, w)
+		}
+	}
+
+	// only regex extractions are evaluated for invalid json
+	values = pi.extract([]byte("build-7"), nil, true)
+	if len(values) != 1 || values[0].Key != "build" || values[0].Value != 7 {
+		t.Errorf("got %+v, want build 7", values)
+	}
+}
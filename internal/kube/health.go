@@ -0,0 +1,172 @@
+package kube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// health formats supported by the health-format annotation
+const (
+	HealthFormatSpring       = "spring"
+	HealthFormatMicroProfile = "microprofile"
+	HealthFormatHealthJSON   = "health+json"
+)
+
+// ComponentStatus is the status of a single component of a health check
+type ComponentStatus struct {
+	Name    string
+	Status  string
+	Healthy bool
+}
+
+// healthy reports whether a status is considered up. IETF health+json
+// allows `ok` and `up` as aliases for `pass`.
+func healthy(status string) bool {
+	switch strings.ToLower(status) {
+	case "up", "pass", "ok":
+		return true
+	}
+	return false
+}
+
+// parseHealthFormat validates the health-format annotation
+func (r *Route) parseHealthFormat(a string) string {
+	a = strings.ToLower(strings.TrimSpace(a))
+	switch a {
+	case HealthFormatSpring, HealthFormatMicroProfile, HealthFormatHealthJSON:
+		return a
+	case "quarkus":
+		return HealthFormatMicroProfile
+	}
+	logrus.Warnf("invalid health format %q %s %s/%s", a, r.ClusterName, r.Namespace, r.Name)
+	return ""
+}
+
+// checkHealth evaluates the parsed body in the configured health format
+// and returns the overall status and the status per component
+func (pi *ProbeInfo) checkHealth(data interface{}) (failed bool, components []ComponentStatus) {
+	if pi.HealthFormat == "" {
+		return false, nil
+	}
+	doc, ok := data.(map[string]interface{})
+	if !ok {
+		logrus.Errorf("%s %s %s", "health response is no json object", pi.Cluster, pi.URL())
+		return true, nil
+	}
+	status, ok := doc["status"].(string)
+	if !ok {
+		logrus.Errorf("%s %s %s", "health response has no status", pi.Cluster, pi.URL())
+		return true, nil
+	}
+	switch pi.HealthFormat {
+	case HealthFormatSpring:
+		components = springComponents("", doc)
+	case HealthFormatMicroProfile:
+		components = microProfileComponents(doc)
+	case HealthFormatHealthJSON:
+		components = healthJSONComponents(doc)
+	}
+	components = mergeComponents(components)
+	if !healthy(status) {
+		msg := fmt.Sprintf("health status %s", status)
+		for _, c := range components {
+			if !c.Healthy {
+				msg += fmt.Sprintf(", %s %s", c.Name, c.Status)
+			}
+		}
+		logrus.Errorf("%s %s %s", msg, pi.Cluster, pi.URL())
+		return true, components
+	}
+	return false, components
+}
+
+// springComponents flattens the nested components of a Spring Boot
+// actuator health response, joining nested names with a slash. Spring Boot
+// before 2.2 uses `details` instead of `components`.
+func springComponents(prefix string, doc map[string]interface{}) (components []ComponentStatus) {
+	children, ok := doc["components"].(map[string]interface{})
+	if !ok {
+		children, _ = doc["details"].(map[string]interface{})
+	}
+	for name, child := range children {
+		c, ok := child.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, ok := c["status"].(string)
+		if !ok {
+			continue
+		}
+		name = prefix + name
+		components = append(components, ComponentStatus{Name: name, Status: status, Healthy: healthy(status)})
+		components = append(components, springComponents(name+"/", c)...)
+	}
+	return
+}
+
+// microProfileComponents returns the checks of a MicroProfile health
+// response
+func microProfileComponents(doc map[string]interface{}) (components []ComponentStatus) {
+	checks, _ := doc["checks"].([]interface{})
+	for _, check := range checks {
+		c, ok := check.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := c["name"].(string)
+		status, ok := c["status"].(string)
+		if name == "" || !ok {
+			continue
+		}
+		components = append(components, ComponentStatus{Name: name, Status: status, Healthy: healthy(status)})
+	}
+	return
+}
+
+// healthJSONComponents returns the checks of an IETF health+json response.
+// Checks are keyed by `component:measurement` and hold a list of
+// observations, which are named by their componentId if there are several.
+func healthJSONComponents(doc map[string]interface{}) (components []ComponentStatus) {
+	checks, _ := doc["checks"].(map[string]interface{})
+	for key, check := range checks {
+		observations, _ := check.([]interface{})
+		for _, observation := range observations {
+			o, ok := observation.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			status, ok := o["status"].(string)
+			if !ok {
+				continue
+			}
+			name := key
+			if id, ok := o["componentId"].(string); ok && len(observations) > 1 {
+				name = key + "/" + id
+			}
+			components = append(components, ComponentStatus{Name: name, Status: status, Healthy: healthy(status)})
+		}
+	}
+	return
+}
+
+// mergeComponents sorts components by name and merges duplicate names into
+// a single unhealthy component if any of them is unhealthy
+func mergeComponents(components []ComponentStatus) (merged []ComponentStatus) {
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+	for _, c := range components {
+		last := len(merged) - 1
+		if last >= 0 && merged[last].Name == c.Name {
+			if merged[last].Healthy && !c.Healthy {
+				merged[last] = c
+			}
+			continue
+		}
+		merged = append(merged, c)
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+func TestParseHealthFormat(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		annotation string
+		format     string
+	}{
+		{"spring", HealthFormatSpring},
+		{" Spring ", HealthFormatSpring},
+		{"microprofile", HealthFormatMicroProfile},
+		{"quarkus", HealthFormatMicroProfile},
+		{"health+json", HealthFormatHealthJSON},
+		{"nagios", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if format := r.parseHealthFormat(tt.annotation); format != tt.format {
+			t.Errorf("%q: got %q, want %q", tt.annotation, format, tt.format)
+		}
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		format     string
+		body       string
+		failed     bool
+		components string
+	}{
+		{
+			HealthFormatSpring,
+			`{"status": "UP", "components": {"db": {"status": "UP", "components": {"primary": {"status": "UP"}}}, "diskSpace": {"status": "UP"}}}`,
+			false, "db=UP,db/primary=UP,diskSpace=UP",
+		},
+		{
+			HealthFormatSpring,
+			`{"status": "DOWN", "details": {"db": {"status": "DOWN"}, "ping": {"status": "UP"}}}`,
+			true, "db=DOWN,ping=UP",
+		},
+		{
+			HealthFormatMicroProfile,
+			`{"status": "UP", "checks": [{"name": "db", "status": "UP"}, {"name": "", "status": "UP"}, {"name": "cache"}]}`,
+			false, "db=UP",
+		},
+		{
+			// duplicate check names are merged into the unhealthy one
+			HealthFormatMicroProfile,
+			`{"status": "DOWN", "checks": [{"name": "db", "status": "UP"}, {"name": "db", "status": "DOWN"}, {"name": "db", "status": "UP"}]}`,
+			true, "db=DOWN",
+		},
+		{
+			HealthFormatHealthJSON,
+			`{"status": "pass", "checks": {"db:responseTime": [{"status": "pass"}], "cache:hits": [{"componentId": "a", "status": "warn"}, {"componentId": "b", "status": "ok"}]}}`,
+			false, "cache:hits/a=warn,cache:hits/b=ok,db:responseTime=pass",
+		},
+		{HealthFormatHealthJSON, `{"status": "fail"}`, true, ""},
+		{HealthFormatSpring, `{"components": {}}`, true, ""},
+		{HealthFormatSpring, `["UP"]`, true, ""},
+	}
+	for _, tt := range tests {
+		var data interface{}
+		if err := json.Unmarshal([]byte(tt.body), &data); err != nil {
+			t.Fatal(err)
+		}
+		pi := &ProbeInfo{HealthFormat: tt.format}
+		failed, components := pi.checkHealth(data)
+		got := []string{}
+		for _, c := range components {
+			got = append(got, fmt.Sprintf("%s=%s", c.Name, c.Status))
+			if c.Healthy != healthy(c.Status) {
+				t.Errorf("%s %s: component %s healthy %t", tt.format, tt.body, c.Name, c.Healthy)
+			}
+		}
+		if failed != tt.failed || strings.Join(got, ",") != tt.components {
+			t.Errorf("%s %s: got failed %t %v, want %t %s", tt.format, tt.body, failed, got, tt.failed, tt.components)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package kube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// jsonOperators are the supported comparisons, longer operators first
+var jsonOperators = []string{"==", "!=", "=~", "<=", ">=", "<", ">"}
+
+// JSONAssertion compares the value at a JSONPath with an expected value
+type JSONAssertion struct {
+	Raw      string
+	Path     string
+	Operator string
+	Value    string
+}
+
+// parseJSONAssertions parses newline seperated json assertions of the form
+// `{.path} <operator> <value>`, a path without operator checks for
+// presence. Invalid lines are skipped.
+func (r *Route) parseJSONAssertions(a string) (assertions []JSONAssertion) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(a, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		path, rest, err := splitJSONPath(line)
+		if err != nil {
+			logrus.Warnf("invalid json assertion %q %s %s %s/%s", line, err, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		ja := JSONAssertion{Raw: line, Path: path}
+		for _, op := range jsonOperators {
+			if strings.HasPrefix(rest, op) {
+				ja.Operator = op
+				ja.Value = strings.TrimSpace(strings.TrimPrefix(rest, op))
+				break
+			}
+		}
+		if rest != "" && ja.Operator == "" {
+			logrus.Warnf("invalid json assertion %q unknown operator %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		if ja.Operator == "=~" {
+			if _, err := regexp.Compile(ja.Value); err != nil {
+				logrus.Warnf("invalid json assertion %q %s %s %s/%s", line, err, r.ClusterName, r.Namespace, r.Name)
+				continue
+			}
+		}
+		assertions = append(assertions, ja)
+	}
+	return
+}
+
+// splitJSONPath splits a leading `{...}` JSONPath expression from the rest
+// of s and validates it
+func splitJSONPath(s string) (path, rest string, err error) {
+	if !strings.HasPrefix(s, "{") {
+		return "", "", errors.New("expression must start with {")
+	}
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			path = s[:i+1]
+			rest = strings.TrimSpace(s[i+1:])
+			break
+		}
+	}
+	if path == "" {
+		return "", "", errors.New("unbalanced braces")
+	}
+	err = jsonpath.New("").Parse(path)
+	return
+}
+
+// jsonPathValue returns the first value at path in data. A new JSONPath is
+// parsed on each call as it is not safe for concurrent use.
+func jsonPathValue(path string, data interface{}) (interface{}, error) {
+	jp := jsonpath.New("")
+	err := jp.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	results, err := jp.FindResults(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, fmt.Errorf("%s not found", path)
+	}
+	return results[0][0].Interface(), nil
+}
+
+// jsonString formats a json value for comparisons
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// check evaluates the assertion against the parsed body
+func (ja JSONAssertion) check(data interface{}) error {
+	v, err := jsonPathValue(ja.Path, data)
+	if err != nil {
+		return err
+	}
+	actual := jsonString(v)
+	ok := true
+	switch ja.Operator {
+	case "":
+		// presence only
+	case "==":
+		ok = actual == ja.Value
+	case "!=":
+		ok = actual != ja.Value
+	case "=~":
+		ok = regexp.MustCompile(ja.Value).MatchString(actual)
+	default:
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return fmt.Errorf("%s is not a number: %q", ja.Path, actual)
+		}
+		e, err := strconv.ParseFloat(ja.Value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", ja.Value)
+		}
+		switch ja.Operator {
+		case "<":
+			ok = a < e
+		case "<=":
+			ok = a <= e
+		case ">":
+			ok = a > e
+		case ">=":
+			ok = a >= e
+		}
+	}
+	if !ok {
+		return fmt.Errorf("json assertion %q failed, got %q", ja.Raw, actual)
+	}
+	return nil
+}
+
+// decodeJSON parses the body if any json rule is configured. A nil result
+// without parse error means no json is required.
+func (pi *ProbeInfo) decodeJSON(body []byte) (data interface{}, parseErr bool) {
+	if !pi.needsJSON() {
+		return nil, false
+	}
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		logrus.Errorf("%s %s %s", err, pi.Cluster, pi.URL())
+		return nil, true
+	}
+	return data, false
+}
+
+// needsJSON reports whether any rule evaluates the body as json
+func (pi *ProbeInfo) needsJSON() bool {
+	if len(pi.JSONAssertions) > 0 || pi.HealthFormat != "" {
+		return true
+	}
+	for _, e := range pi.Extractions {
+		if e.Path != "" {
+			return true
+		}
+	}
+	return pi.VersionRule != nil && pi.VersionRule.Path != ""
+}
+
+// assertJSON evaluates all json assertions against the parsed body
+func (pi *ProbeInfo) assertJSON(data interface{}) (results []AssertionResult) {
+	for _, ja := range pi.JSONAssertions {
+		err := ja.check(data)
+		if err != nil {
+			logrus.Errorf("%s %s %s", err, pi.Cluster, pi.URL())
+		}
+		results = append(results, AssertionResult{Name: ja.Raw, Failed: err != nil})
+	}
+	return
+}
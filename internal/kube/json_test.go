@@ -0,0 +1,101 @@
+package kube
+
+import (
+	"encoding/json"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+func TestParseJSONAssertions(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		line     string
+		valid    bool
+		path     string
+		operator string
+		value    string
+	}{
+		{"{.status} == UP", true, "{.status}", "==", "UP"},
+		{"{.status} != DOWN", true, "{.status}", "!=", "DOWN"},
+		{"{.version} =~ ^1\\.", true, "{.version}", "=~", "^1\\."},
+		{"{.queue.depth} <= 100", true, "{.queue.depth}", "<=", "100"},
+		{"{.queue.depth} < 100", true, "{.queue.depth}", "<", "100"},
+		{"{.queue.depth} >= 1", true, "{.queue.depth}", ">=", "1"},
+		{"{.queue.depth} > 1", true, "{.queue.depth}", ">", "1"},
+		{"{.checks[0].name}", true, "{.checks[0].name}", "", ""},
+		{"{.items[?(@.name==\"db\")].status} == UP", true, "{.items[?(@.name==\"db\")].status}", "==", "UP"},
+		{".status == UP", false, "", "", ""},
+		{"{.status == UP", false, "", "", ""},
+		{"{.status} is UP", false, "", "", ""},
+		{"{.version} =~ (", false, "", "", ""},
+		{"{.status[} == UP", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		assertions := r.parseJSONAssertions(tt.line)
+		if !tt.valid {
+			if len(assertions) != 0 {
+				t.Errorf("%q: got %+v, want rejected", tt.line, assertions)
+			}
+			continue
+		}
+		if len(assertions) != 1 {
+			t.Errorf("%q: got %d assertions, want 1", tt.line, len(assertions))
+			continue
+		}
+		ja := assertions[0]
+		if ja.Path != tt.path || ja.Operator != tt.operator || ja.Value != tt.value {
+			t.Errorf("%q: got %q %q %q, want %q %q %q", tt.line, ja.Path, ja.Operator, ja.Value, tt.path, tt.operator, tt.value)
+		}
+	}
+}
+
+func TestParseJSONAssertionsDuplicates(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	assertions := r.parseJSONAssertions("{.status} == UP\n\n  {.status} == UP\n{.status} != DOWN")
+	if len(assertions) != 2 {
+		t.Errorf("got %d assertions, want 2", len(assertions))
+	}
+}
+
+func TestJSONAssertionCheck(t *testing.T) {
+	var data interface{}
+	body := `{"status": "UP", "depth": 42, "ratio": 0.5, "up": true, "none": null, "tags": ["a"], "version": "1.2.3"}`
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		t.Fatal(err)
+	}
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		line string
+		ok   bool
+	}{
+		{"{.status} == UP", true},
+		{"{.status} == DOWN", false},
+		{"{.status} != DOWN", true},
+		{"{.depth} == 42", true},
+		{"{.ratio} == 0.5", true},
+		{"{.up} == true", true},
+		{"{.none} == null", true},
+		{`{.tags} == ["a"]`, true},
+		{"{.version} =~ ^1\\.2", true},
+		{"{.version} =~ ^2", false},
+		{"{.depth} < 42", false},
+		{"{.depth} <= 42", true},
+		{"{.depth} > 41.5", true},
+		{"{.depth} >= 43", false},
+		{"{.status} < 1", false},
+		{"{.depth} < many", false},
+		{"{.depth}", true},
+		{"{.missing}", false},
+	}
+	for _, tt := range tests {
+		assertions := r.parseJSONAssertions(tt.line)
+		if len(assertions) != 1 {
+			t.Errorf("%q: got %d assertions, want 1", tt.line, len(assertions))
+			continue
+		}
+		if err := assertions[0].check(data); (err == nil) != tt.ok {
+			t.Errorf("%q: got error %v, want ok %t", tt.line, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// insecurePolicyTimeout limits the plain http request, port 80 is often
+// firewalled by dropping packets
+const insecurePolicyTimeout = 5 * time.Second
+
+// insecurePolicyMaxBody limits the body read to detect router error pages
+const insecurePolicyMaxBody = 64 << 10
+
+// insecurePolicy is the result of the plain http probe
+type insecurePolicy struct {
+	Policy   string
+	Violated bool
+}
+
+// probeInsecurePolicy checks the spec.tls.insecureEdgeTerminationPolicy of
+// tls Routes with an additional plain http request. Redirect must redirect
+// to the https url, Allow must serve content and None must not serve content.
+// The request is a plain GET without credentials, headers or body, as these
+// were only configured to be sent over tls. A response counts as served
+// unless it is the router's error page or a redirect to https, so Routes
+// behind authentication are judged correctly. It runs alongside the main
+// probe in its own slot, ip versions skipped by the main probe are skipped
+// as well.
+func (r *Route) probeInsecurePolicy(ctx context.Context, pi *ProbeInfo) (result insecurePolicy) {
+	if r.Spec.TLS == nil || r.skipIPVersion(ctx, pi) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, insecurePolicyTimeout)
+	defer cancel()
+	policy := r.Spec.TLS.InsecureEdgeTerminationPolicy
+	if policy == "" {
+		policy = routev1.InsecureEdgeTerminationPolicyNone
+	}
+	result.Policy = string(policy)
+
+	insecure := *pi
+	insecure.Proto = "http"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, insecure.URL(), nil)
+	if err != nil {
+		logrus.Errorf("%s %s %s", err, pi.Cluster, insecure.URL())
+		return
+	}
+	client := http.Client{
+		Transport: r.newTransport(pi, nil, nil),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	served := false
+	redirected := false
+	if err == nil {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, insecurePolicyMaxBody))
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			redirected = redirectsToHTTPS(resp.Header.Get("Location"), pi.Host)
+		}
+		served = !redirected && !r.isRouterErrorPage(resp.StatusCode, body)
+	}
+
+	switch policy {
+	case routev1.InsecureEdgeTerminationPolicyRedirect:
+		result.Violated = !redirected
+	case routev1.InsecureEdgeTerminationPolicyAllow:
+		result.Violated = !served
+	case routev1.InsecureEdgeTerminationPolicyNone:
+		result.Violated = served
+	}
+	if result.Violated {
+		msg := fmt.Sprintf("insecure edge termination policy %s violated", policy)
+		logrus.Errorf("%s %s %s", msg, pi.Cluster, insecure.URL())
+	}
+	return
+}
+
+// redirectsToHTTPS reports whether location points to https on host
+func redirectsToHTTPS(location, host string) bool {
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "https" && strings.EqualFold(u.Hostname(), hostname(host))
+}
@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+const routerErrorPage = `<html><body><h1>Application is not available</h1></body></html>`
+
+func TestProbeInsecurePolicy(t *testing.T) {
+	redirect := routev1.InsecureEdgeTerminationPolicyRedirect
+	allow := routev1.InsecureEdgeTerminationPolicyAllow
+	none := routev1.InsecureEdgeTerminationPolicyNone
+	tests := []struct {
+		policy   routev1.InsecureEdgeTerminationPolicyType
+		status   int
+		location string
+		body     string
+		// refused closes the server before the request
+		refused  bool
+		violated bool
+	}{
+		{redirect, http.StatusMovedPermanently, "https://%s/", "", false, false},
+		{redirect, http.StatusFound, "https://%s/login", "", false, false},
+		{redirect, http.StatusTemporaryRedirect, "https://%s/", "", false, false},
+		{redirect, http.StatusPermanentRedirect, "https://%s/", "", false, false},
+		{redirect, http.StatusMovedPermanently, "https://other.example.com/", "", false, true},
+		{redirect, http.StatusMovedPermanently, "http://%s/", "", false, true},
+		{redirect, http.StatusSeeOther, "https://%s/", "", false, true},
+		{redirect, http.StatusOK, "", "content", false, true},
+		{redirect, 0, "", "", true, true},
+		{allow, http.StatusOK, "", "content", false, false},
+		{allow, http.StatusUnauthorized, "", "login required", false, false},
+		{allow, http.StatusServiceUnavailable, "", routerErrorPage, false, true},
+		{allow, http.StatusMovedPermanently, "https://%s/", "", false, true},
+		{allow, 0, "", "", true, true},
+		{none, http.StatusServiceUnavailable, "", routerErrorPage, false, false},
+		{none, http.StatusMovedPermanently, "https://%s/", "", false, false},
+		{none, http.StatusOK, "", "content", false, true},
+		{none, http.StatusUnauthorized, "", "login required", false, true},
+		{none, 0, "", "", true, false},
+		// a missing policy is None
+		{"", http.StatusOK, "", "content", false, true},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if tt.location != "" {
+				// %s is the host the request was sent to
+				w.Header().Set("Location", fmt.Sprintf(tt.location, req.Host))
+			}
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		host := server.Listener.Addr().String()
+		if tt.refused {
+			server.Close()
+		}
+
+		r := &Route{Route: &routev1.Route{Spec: routev1.RouteSpec{
+			Host: host,
+			TLS: &routev1.TLSConfig{
+				Termination:                   routev1.TLSTerminationEdge,
+				InsecureEdgeTerminationPolicy: tt.policy,
+			},
+		}}}
+		result := r.probeInsecurePolicy(context.Background(), &ProbeInfo{Host: host, Path: "/"})
+		server.Close()
+
+		wantPolicy := string(tt.policy)
+		if wantPolicy == "" {
+			wantPolicy = string(none)
+		}
+		if result.Policy != wantPolicy || result.Violated != tt.violated {
+			t.Errorf("%s %d %q refused %t: got %+v, want violated %t", tt.policy, tt.status, tt.location, tt.refused, result, tt.violated)
+		}
+	}
+}
+
+func TestProbeInsecurePolicyWithoutTLS(t *testing.T) {
+	r := &Route{Route: &routev1.Route{Spec: routev1.RouteSpec{Host: "app.example.com"}}}
+	if result := r.probeInsecurePolicy(context.Background(), &ProbeInfo{Host: "app.example.com"}); result != (insecurePolicy{}) {
+		t.Errorf("got %+v, want no policy probe", result)
+	}
+}
+
+func TestRedirectsToHTTPS(t *testing.T) {
+	tests := []struct {
+		location string
+		host     string
+		ok       bool
+	}{
+		{"https://app.example.com/", "app.example.com", true},
+		{"https://APP.example.com:443/login", "app.example.com", true},
+		{"https://app.example.com/", "app.example.com:80", true},
+		{"http://app.example.com/", "app.example.com", false},
+		{"https://evil.example.com/", "app.example.com", false},
+		{"https://app.example.com.evil.com/", "app.example.com", false},
+		{"/login", "app.example.com", false},
+		{"", "app.example.com", false},
+		{"https://[::1/", "app.example.com", false},
+	}
+	for _, tt := range tests {
+		if ok := redirectsToHTTPS(tt.location, tt.host); ok != tt.ok {
+			t.Errorf("%q on %s: got %t, want %t", tt.location, tt.host, ok, tt.ok)
+		}
+	}
+	// hostnames are compared without port
+	if !redirectsToHTTPS("https://"+net.JoinHostPort("127.0.0.1", "8443")+"/", "127.0.0.1:8080") {
+		t.Error("port must not matter")
+	}
+}
@@ -0,0 +1,191 @@
+package kube
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
+)
+
+type (
+	// ProxyConfig configures the egress proxy used to reach a target's
+	// Routes, supported schemes are http (CONNECT) and socks5
+	ProxyConfig struct {
+		URL      string   `yaml:"url"`
+		Username string   `yaml:"username"`
+		Password string   `yaml:"password"`
+		NoProxy  []string `yaml:"no_proxy"`
+	}
+
+	// egressProxy is a parsed ProxyConfig
+	egressProxy struct {
+		url     *url.URL
+		noProxy []string
+	}
+
+	// proxyError marks errors caused by the proxy
+	proxyError struct {
+		err error
+	}
+)
+
+func (e *proxyError) Error() string { return fmt.Sprintf("proxy: %s", e.err) }
+func (e *proxyError) Unwrap() error { return e.err }
+
+// newEgressProxy parses a ProxyConfig, returns nil if no proxy is configured
+func newEgressProxy(c ProxyConfig) (*egressProxy, error) {
+	if c.URL == "" {
+		return nil, nil
+	}
+	u, err := parseProxyURL(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return &egressProxy{url: u, noProxy: c.NoProxy}, nil
+}
+
+// parseProxyURL parses and validates a proxy url, adding the default port.
+// Parse errors are not returned as they contain parts of the url, which may
+// include the proxy password.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.New("invalid proxy url")
+	}
+	port := ""
+	switch u.Scheme {
+	case "http":
+		port = "3128"
+	case "socks5":
+		port = "1080"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
+
+// bypass checks if host matches the no proxy list. Entries match the exact
+// host, subdomains of a domain, addresses in a cidr or `*` for all hosts.
+func (p *egressProxy) bypass(host string) bool {
+	host = strings.ToLower(hostname(host))
+	ip := net.ParseIP(host)
+	for _, entry := range p.noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" || entry == host {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyURL returns the proxy for the Route from the annotation or the
+// target's config, nil if the Route is reached directly
+func (r *Route) proxyURL() *url.URL {
+	if ap, ok := r.GetAnnotations()["thobits.com/ormon-proxy"]; ok {
+		if ap == "none" {
+			return nil
+		}
+		u, err := parseProxyURL(ap)
+		if err != nil {
+			logrus.Warnf("invalid thobits.com/ormon-proxy %s %s %s/%s", err, r.ClusterName, r.Namespace, r.Name)
+			return nil
+		}
+		return u
+	}
+	if r.watcher == nil || r.watcher.proxy == nil || r.watcher.proxy.bypass(r.Spec.Host) {
+		return nil
+	}
+	return r.watcher.proxy.url
+}
+
+// dialProxy opens a tunnel through the proxy to addr
+func dialProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	switch proxyURL.Scheme {
+	case "socks5":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		d, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialer)
+		if err != nil {
+			return nil, &proxyError{err}
+		}
+		conn, err := d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, &proxyError{err}
+		}
+		return conn, nil
+	case "http":
+		conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+		if err != nil {
+			return nil, &proxyError{err}
+		}
+		err = httpConnect(ctx, conn, proxyURL, addr)
+		if err != nil {
+			conn.Close()
+			return nil, &proxyError{err}
+		}
+		return conn, nil
+	}
+	return nil, &proxyError{fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)}
+}
+
+// httpConnect requests a tunnel to addr from a http proxy
+func httpConnect(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) error {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := fmt.Sprintf("%s:%s", proxyURL.User.Username(), password)
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	err := req.Write(conn)
+	if err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CONNECT %s returned %s", addr, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProxyURL(t *testing.T) {
+	tests := []struct {
+		raw   string
+		valid bool
+		host  string
+	}{
+		{"http://proxy.example.com", true, "proxy.example.com:3128"},
+		{"http://proxy.example.com:8080", true, "proxy.example.com:8080"},
+		{"socks5://proxy.example.com", true, "proxy.example.com:1080"},
+		{"socks5://[2001:db8::1]", true, "[2001:db8::1]:1080"},
+		{"https://proxy.example.com", false, ""},
+		{"proxy.example.com:3128", false, ""},
+		{"http://ormon:se cret%@proxy.example.com", false, ""},
+	}
+	for _, tt := range tests {
+		u, err := parseProxyURL(tt.raw)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("%q: got %s, want rejected", tt.raw, u)
+			} else if strings.Contains(err.Error(), "cret") {
+				t.Errorf("%q: error %q contains the password", tt.raw, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.raw, err)
+			continue
+		}
+		if u.Host != tt.host {
+			t.Errorf("%q: got host %s, want %s", tt.raw, u.Host, tt.host)
+		}
+	}
+}
+
+func TestProxyBypass(t *testing.T) {
+	p := &egressProxy{noProxy: []string{
+		"internal.example.com",
+		" .corp.example.com ",
+		"10.0.0.0/8",
+		"",
+	}}
+	tests := []struct {
+		host   string
+		bypass bool
+	}{
+		{"internal.example.com", true},
+		{"INTERNAL.example.com:443", true},
+		{"app.internal.example.com", true},
+		{"notinternal.example.com", false},
+		{"app.corp.example.com", true},
+		{"corp.example.com", false},
+		{"10.1.2.3", true},
+		{"10.1.2.3:8443", true},
+		{"192.0.2.1", false},
+		{"example.com", false},
+		{"example.com.", false},
+	}
+	for _, tt := range tests {
+		if bypass := p.bypass(tt.host); bypass != tt.bypass {
+			t.Errorf("%s: got bypass %t, want %t", tt.host, bypass, tt.bypass)
+		}
+	}
+
+	all := &egressProxy{noProxy: []string{"*"}}
+	if !all.bypass("app.example.com") {
+		t.Error("* must bypass every host")
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	routev1 "github.com/openshift/api/route/v1"
@@ -23,6 +26,8 @@ type (
 	Route struct {
 		*routev1.Route
 		ClusterName string
+
+		watcher *Watcher
 	}
 
 	// ProbeInfo
@@ -34,12 +39,40 @@ type (
 		Proto string
 		Path  string
 
+		Wildcard      string
+		WildcardHosts []string
+		Router        string
+		RouterAddress string
+		Routers       []RouterInfo
+		IPVersion     string
+		DualStack     bool
+		ProxyURL      *url.URL
+		NotAdmitted   bool
+
 		Name      string
 		Namespace string
 
 		Method           string
 		ValidStatusCodes []string
 		BodyRegex        string
+		HeaderAssertions []HeaderAssertion
+		BodyAssertions   []BodyAssertion
+		JSONAssertions   []JSONAssertion
+		Extractions      []Extraction
+		VersionRule      *VersionRule
+		HealthFormat     string
+		Headers          http.Header
+		Body             string
+		ContentType      string
+		AuthSecret       string
+		AuthType         string
+		AuthHeader       string
+		ClientCertSecret string
+
+		Interval            time.Duration
+		Timeout             time.Duration
+		ConnectTimeout      time.Duration
+		TLSHandshakeTimeout time.Duration
 
 		Cluster string
 		UID     string
@@ -49,23 +82,50 @@ type (
 	RequestMetrics struct {
 		*ProbeInfo
 
-		Start         time.Time
-		Resolved      time.Duration
-		Connected     time.Duration
-		WroteRequest  time.Duration
-		ReadFirstByte time.Duration
-		ReadBody      time.Duration
-		Expires       time.Time
-		Size          int64
-		RedirectCount int64
-
-		InvalidRouteErr      bool
-		InvalidRequestErr    bool
-		ConnectionErr        bool
-		BodyDownloadErr      bool
-		InvalidStatusCodeErr bool
-		InvalidBodyRegexErr  bool
-		InvalidBodyErr       bool
+		Probed          time.Time
+		Start           time.Time
+		Resolved        time.Duration
+		Connected       time.Duration
+		ProxyTunnel     time.Duration
+		WroteRequest    time.Duration
+		ReadFirstByte   time.Duration
+		ReadBody        time.Duration
+		Expires         time.Time
+		SSLVerifyReason string
+		TLSHandshake    time.Duration
+		CNAMEDepth      int
+		TLS             *TLSInfo
+		PeerCertificate *x509.Certificate
+		InsecurePolicy  string
+		Size            int64
+		RedirectCount   int64
+		HeaderResults   []AssertionResult
+		BodyResults     []AssertionResult
+		JSONResults     []AssertionResult
+		Extracted       []ExtractedValue
+		Version         string
+		Components      []ComponentStatus
+
+		InvalidRouteErr       bool
+		InvalidRequestErr     bool
+		NotAdmittedErr        bool
+		AuthErr               bool
+		ClientCertErr         bool
+		DNSMismatchErr        bool
+		ConnectionErr         bool
+		ProxyErr              bool
+		ClientCertRejectedErr bool
+		SSLVerifyErr          bool
+		SSLCertMismatchErr    bool
+		DefaultRouterCert     bool
+		InsecurePolicyErr     bool
+		BodyDownloadErr       bool
+		InvalidStatusCodeErr  bool
+		InvalidBodyRegexErr   bool
+		RouterNoBackendErr    bool
+		JSONParseErr          bool
+		HealthErr             bool
+		InvalidBodyErr        bool
 	}
 )
 
@@ -99,6 +159,71 @@ func (r *Route) getProbeInfo() *ProbeInfo {
 	if abr, ok := r.GetAnnotations()["thobits.com/ormon-body-regex"]; ok {
 		bodyRegex = abr
 	}
+	headerAssertions := []HeaderAssertion{}
+	if aha, ok := r.GetAnnotations()["thobits.com/ormon-header-assertions"]; ok {
+		headerAssertions = r.parseHeaderAssertions(aha)
+	}
+	bodyAssertions := []BodyAssertion{}
+	if aba, ok := r.GetAnnotations()["thobits.com/ormon-body-assertions"]; ok {
+		bodyAssertions = r.parseBodyAssertions(aba)
+	}
+	jsonAssertions := []JSONAssertion{}
+	if aja, ok := r.GetAnnotations()["thobits.com/ormon-json-assertions"]; ok {
+		jsonAssertions = r.parseJSONAssertions(aja)
+	}
+	extractions := []Extraction{}
+	if ae, ok := r.GetAnnotations()["thobits.com/ormon-extract"]; ok {
+		extractions = r.parseExtractions(ae)
+	}
+	var versionRule *VersionRule
+	if av, ok := r.GetAnnotations()["thobits.com/ormon-version"]; ok {
+		versionRule = r.parseVersionRule(av)
+	}
+	healthFormat := ""
+	if ahf, ok := r.GetAnnotations()["thobits.com/ormon-health-format"]; ok {
+		healthFormat = r.parseHealthFormat(ahf)
+	}
+	headers := http.Header{}
+	if ah, ok := r.GetAnnotations()["thobits.com/ormon-headers"]; ok {
+		headers = r.parseHeaders(ah)
+	}
+	body := ""
+	if ab, ok := r.GetAnnotations()["thobits.com/ormon-body"]; ok {
+		body = ab
+	}
+	contentType := ""
+	if act, ok := r.GetAnnotations()["thobits.com/ormon-content-type"]; ok {
+		contentType = act
+	}
+	authSecret := ""
+	if aas, ok := r.GetAnnotations()["thobits.com/ormon-auth-secret"]; ok {
+		authSecret = aas
+	}
+	authType := "basic"
+	if aat, ok := r.GetAnnotations()["thobits.com/ormon-auth-type"]; ok {
+		authType = strings.ToLower(aat)
+	}
+	authHeader := "Authorization"
+	if aah, ok := r.GetAnnotations()["thobits.com/ormon-auth-header"]; ok {
+		authHeader = aah
+	}
+	clientCertSecret := ""
+	if accs, ok := r.GetAnnotations()["thobits.com/ormon-client-cert-secret"]; ok {
+		clientCertSecret = accs
+	}
+	wildcard, wildcardHosts := r.wildcardHosts()
+	routers := r.routers()
+	dualStack := false
+	if r.watcher != nil {
+		dualStack = r.watcher.dualStack
+	}
+	if ads, ok := r.GetAnnotations()["thobits.com/ormon-dual-stack"]; ok {
+		dualStack, _ = strconv.ParseBool(ads)
+	}
+	interval := r.durationAnnotation("thobits.com/ormon-interval")
+	timeout := r.durationAnnotation("thobits.com/ormon-timeout")
+	connectTimeout := r.durationAnnotation("thobits.com/ormon-connect-timeout")
+	tlsHandshakeTimeout := r.durationAnnotation("thobits.com/ormon-tls-handshake-timeout")
 
 	return &ProbeInfo{
 		Skip: skip,
@@ -107,12 +232,37 @@ func (r *Route) getProbeInfo() *ProbeInfo {
 		Proto: proto,
 		Path:  path,
 
+		Wildcard:      wildcard,
+		WildcardHosts: wildcardHosts,
+		Routers:       routers,
+		DualStack:     dualStack,
+		ProxyURL:      r.proxyURL(),
+		NotAdmitted:   !r.Admitted(),
+
 		Name:      r.Name,
 		Namespace: r.Namespace,
 
 		Method:           method,
 		ValidStatusCodes: validStatusCodes,
 		BodyRegex:        bodyRegex,
+		HeaderAssertions: headerAssertions,
+		BodyAssertions:   bodyAssertions,
+		JSONAssertions:   jsonAssertions,
+		Extractions:      extractions,
+		VersionRule:      versionRule,
+		HealthFormat:     healthFormat,
+		Headers:          headers,
+		Body:             body,
+		ContentType:      contentType,
+		AuthSecret:       authSecret,
+		AuthType:         authType,
+		AuthHeader:       authHeader,
+		ClientCertSecret: clientCertSecret,
+
+		Interval:            interval,
+		Timeout:             timeout,
+		ConnectTimeout:      connectTimeout,
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
 
 		SSL:     ssl,
 		Cluster: r.ClusterName,
@@ -120,33 +270,177 @@ func (r *Route) getProbeInfo() *ProbeInfo {
 	}
 }
 
-// Probe gathers the metrics for a route
-func (r *Route) Probe(ctx context.Context) (m *RequestMetrics) {
+// parseHeaders parses newline seperated `Name: value` pairs, invalid lines
+// are skipped
+func (r *Route) parseHeaders(a string) http.Header {
+	headers := http.Header{}
+	for _, line := range strings.Split(a, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			logrus.Warnf("invalid header %q %s %s/%s", line, r.ClusterName, r.Namespace, r.Name)
+			continue
+		}
+		headers.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	return headers
+}
+
+// durationAnnotation parses a duration annotation, returns 0 if the
+// annotation is missing or invalid
+func (r *Route) durationAnnotation(key string) time.Duration {
+	a, ok := r.GetAnnotations()[key]
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(a)
+	if err != nil || d < 0 {
+		logrus.Warnf("invalid %s %q %s %s/%s", key, a, r.ClusterName, r.Namespace, r.Name)
+		return 0
+	}
+	return d
+}
+
+// Schedule returns the probe info of the route and its probe interval,
+// falling back to the supplied defaults. The timeout of the returned probe
+// info is set to the route's timeout or defaultTimeout.
+func (r *Route) Schedule(defaultInterval, defaultTimeout time.Duration) (pi *ProbeInfo, interval time.Duration) {
+	pi = r.getProbeInfo()
+	interval = defaultInterval
+	if pi.Interval > 0 {
+		interval = pi.Interval
+	}
+	if pi.Timeout <= 0 {
+		pi.Timeout = defaultTimeout
+	}
+	return
+}
+
+// Probe gathers the metrics for a route described by pi, wildcard Routes
+// are probed once per sample host. Every single request, including the plain
+// http request of the insecure policy and the dns verification of each host,
+// occupies a slot of sem while it runs and is limited to pi.Timeout from the
+// moment it got its slot. Requests that get no slot before ctx is done and ip
+// versions only the other ip version has addresses for are dropped.
+func (r *Route) Probe(ctx context.Context, pi *ProbeInfo, sem chan struct{}) (ms []*RequestMetrics) {
+	if pi.Skip {
+		return nil
+	}
+
+	pis := pi.expand()
+	results := make([]*RequestMetrics, len(pis))
+	policies := make([]insecurePolicy, len(pis))
+	wg := sync.WaitGroup{}
+
+	// dns only depends on the host, it is verified once per host
+	hosts := map[string]int{}
+	for _, pi := range pis {
+		if _, ok := hosts[hostname(pi.Host)]; !ok && !pi.NotAdmitted {
+			hosts[hostname(pi.Host)] = len(hosts)
+		}
+	}
+	cnameDepths := make([]int, len(hosts))
+	dnsMismatches := make([]bool, len(hosts))
+	for host, i := range hosts {
+		host, i := host, i
+		inSlot(ctx, &wg, sem, pi.Timeout, func(ctx context.Context) {
+			cnameDepths[i], dnsMismatches[i] = r.verifyDNS(ctx, host, pi.ProxyURL != nil)
+		})
+	}
+
+	for i, pi := range pis {
+		i, pi := i, pi
+		inSlot(ctx, &wg, sem, pi.Timeout, func(ctx context.Context) {
+			results[i] = r.probe(ctx, pi)
+		})
+		if r.Spec.TLS != nil && !pi.NotAdmitted {
+			inSlot(ctx, &wg, sem, pi.Timeout, func(ctx context.Context) {
+				policies[i] = r.probeInsecurePolicy(ctx, pi)
+			})
+		}
+	}
+	wg.Wait()
+	for i, m := range results {
+		if m != nil {
+			m.InsecurePolicy = policies[i].Policy
+			m.InsecurePolicyErr = policies[i].Violated
+			if h, ok := hosts[hostname(m.Host)]; ok {
+				m.CNAMEDepth = cnameDepths[h]
+				m.DNSMismatchErr = dnsMismatches[h]
+			}
+			ms = append(ms, m)
+		}
+	}
+	return
+}
+
+// inSlot runs f in the background once it got a slot of sem, limited to
+// timeout. f is not run if ctx is done before.
+func inSlot(ctx context.Context, wg *sync.WaitGroup, sem chan struct{}, timeout time.Duration, f func(ctx context.Context)) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		f(ctx)
+	}()
+}
+
+// probe gathers the metrics for a single host of the route
+func (r *Route) probe(ctx context.Context, pi *ProbeInfo) (m *RequestMetrics) {
 	// prepare
-	pi := r.getProbeInfo()
-	m = &RequestMetrics{ProbeInfo: pi}
-	if m.Skip {
+	m = &RequestMetrics{ProbeInfo: pi, Probed: time.Now()}
+	if m.NotAdmitted {
+		// no router serves the route, the probe can only fail
+		m.NotAdmittedErr = true
+		logrus.Errorf("%s %s %s", "route not admitted by any router", m.Cluster, m.URL())
+		return
+	}
+	if r.skipIPVersion(ctx, pi) {
+		// single stack hosts are only probed over their ip version
+		logrus.Debugf("%s has no ipv%s address %s", m.Host, pi.IPVersion, m.Cluster)
 		return nil
 	}
-	req, err := http.NewRequest(m.Method, m.URL(), nil)
+	req, err := pi.newRequest(ctx, m.URL())
 	if err != nil {
 		m.InvalidRequestErr = true
 		logrus.Errorf("%s %s %s", err, m.Cluster, m.URL())
 		return
 	}
-	req = req.WithContext(ctx)
+	err = r.authenticate(ctx, req, pi)
+	if err != nil {
+		m.AuthErr = true
+		logrus.Errorf("%s %s %s", err, m.Cluster, m.URL())
+		return
+	}
 
 	// metrics storage
+	tlsHandshakeStart := time.Time{}
 	trace := &httptrace.ClientTrace{
-		DNSStart: func(dsi httptrace.DNSStartInfo) {
-			m.Start = time.Now()
-		},
 		DNSDone: func(ddi httptrace.DNSDoneInfo) {
 			m.Resolved = time.Since(m.Start)
 		},
 		ConnectDone: func(network, addr string, err error) {
 			m.Connected = time.Since(m.Start)
 		},
+		TLSHandshakeStart: func() {
+			tlsHandshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			m.TLSHandshake = time.Since(tlsHandshakeStart)
+		},
 		WroteRequest: func(wri httptrace.WroteRequestInfo) {
 			m.WroteRequest = time.Since(m.Start)
 		},
@@ -156,37 +450,59 @@ func (r *Route) Probe(ctx context.Context) (m *RequestMetrics) {
 	}
 
 	// request
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	tlsConfig, err := r.tlsConfig(ctx, m)
+	if err != nil {
+		m.ClientCertErr = true
+		logrus.Errorf("%s %s %s", err, m.Cluster, m.URL())
+		return
+	}
 	client := http.Client{
-		Transport: http.DefaultTransport,
+		Transport: r.newTransport(pi, tlsConfig, func() {
+			m.ProxyTunnel = time.Since(m.Start)
+		}),
 		CheckRedirect: func(r *http.Request, via []*http.Request) error {
 			redirects := len(via)
 			m.RedirectCount = int64(redirects)
 			if redirects > 10 {
 				return fmt.Errorf("to many redirects (%d)", redirects)
 			}
+			pi.stripCredentials(r, via)
 			return nil
 		},
 	}
 	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	m.Start = time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
 		m.ConnectionErr = true
+		m.ClientCertRejectedErr = isClientCertRejected(err)
+		m.ProxyErr = errors.As(err, new(*proxyError))
 		logrus.Errorf("%s %s %s", err, m.Cluster, m.Host)
 		return
 	}
+	defer resp.Body.Close()
 
-	// statuscode
-	statusCodeIsValid := false
-	for _, sc := range m.ValidStatusCodes {
-		statusCodeIsValid = statusCodeIsValid || (sc == strconv.Itoa(resp.StatusCode))
+	// ssl
+	m.Expires = time.Time{}
+	if resp.TLS != nil {
+		m.Expires = expiresFirst(resp.TLS.PeerCertificates)
+		m.TLS = newTLSInfo(resp.TLS)
 	}
-	if !statusCodeIsValid {
+	m.SSLCertMismatchErr, m.DefaultRouterCert = r.compareServedCertificate(m.PeerCertificate)
+	if m.SSLCertMismatchErr {
+		logrus.Errorf("%s %s %s", "served certificate does not match spec.tls.certificate", m.Cluster, m.URL())
+	}
+
+	// statuscode
+	if !pi.validStatusCode(resp.StatusCode) {
 		m.InvalidStatusCodeErr = true
 		msg := fmt.Sprintf("statuscode %d not in %s", resp.StatusCode, strings.Join(m.ValidStatusCodes, ","))
 		logrus.Errorf("%s %s %s", msg, m.Cluster, m.URL())
 	}
 
+	// headers
+	m.HeaderResults = pi.assertHeaders(resp.Header)
+
 	// read body
 	body := bytes.NewBufferString("")
 	respBodyReader := newCtxReader(ctx, resp.Body)
@@ -197,26 +513,87 @@ func (r *Route) Probe(ctx context.Context) (m *RequestMetrics) {
 		return
 	}
 	m.ReadBody = time.Since(m.Start)
+
+	// router error page
+	if r.isRouterErrorPage(resp.StatusCode, body.Bytes()) {
+		m.RouterNoBackendErr = true
+		logrus.Errorf("%s %s %s", "router has no backend", m.Cluster, m.URL())
+	}
+
+	// body assertions
+	m.BodyResults = pi.assertBody(body.Bytes())
+
+	// json
+	data, parseErr := pi.decodeJSON(body.Bytes())
+	m.JSONParseErr = parseErr
+	if !parseErr {
+		m.JSONResults = pi.assertJSON(data)
+	}
+
+	// health
+	if pi.HealthFormat != "" {
+		m.HealthErr = parseErr
+		if !parseErr {
+			m.HealthErr, m.Components = pi.checkHealth(data)
+		}
+	}
+
+	// extractions
+	m.Extracted = pi.extract(body.Bytes(), data, parseErr)
+
+	// version
+	m.Version = pi.captureVersion(resp.Header, body.Bytes(), data, parseErr)
+
+	// body regex
 	bodyRegex, err := regexp.Compile(m.BodyRegex)
 	if err != nil {
 		m.InvalidBodyRegexErr = true
 		logrus.Errorf("%s %s %s", err, m.Cluster, m.URL())
 		return
 	}
-	match := bodyRegex.FindReaderIndex(body)
-	if match == nil {
+	match := bodyRegex.Match(body.Bytes())
+	if !match {
 		m.InvalidBodyErr = true
 		logrus.Errorf("%s %s %s", "body regex does not match", m.Cluster, m.URL())
 		return
 	}
 
-	// ssl
-	m.Expires = time.Time{}
-	if resp.TLS != nil {
-		m.Expires = expiresFirst(resp.TLS.PeerCertificates)
+	return m
+}
+
+// newRequest builds a request for url with the configured method, body and
+// headers
+func (pi *ProbeInfo) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	var reqBody io.Reader
+	if pi.Body != "" {
+		reqBody = strings.NewReader(pi.Body)
 	}
+	req, err := http.NewRequest(pi.Method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range pi.Headers {
+		if k == "Host" {
+			req.Host = vs[0]
+			continue
+		}
+		req.Header[k] = vs
+	}
+	if pi.ContentType != "" {
+		req.Header.Set("Content-Type", pi.ContentType)
+	}
+	return req, nil
+}
 
-	return m
+// validStatusCode checks if statusCode is one of the ValidStatusCodes
+func (pi *ProbeInfo) validStatusCode(statusCode int) bool {
+	for _, sc := range pi.ValidStatusCodes {
+		if sc == strconv.Itoa(statusCode) {
+			return true
+		}
+	}
+	return false
 }
 
 func (pi *ProbeInfo) URL() string {
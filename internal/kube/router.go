@@ -0,0 +1,81 @@
+package kube
+
+import (
+	"strconv"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type (
+	// RouterInfo identifies a router that exposes the Route
+	RouterInfo struct {
+		Name              string
+		CanonicalHostname string
+	}
+
+	// Admission is the admission state of the Route on a single router
+	Admission struct {
+		Router   string
+		Admitted bool
+		Reason   string
+	}
+)
+
+// Admissions returns the admission state of every router in status.ingress
+func (r *Route) Admissions() (admissions []Admission) {
+	for _, ingress := range r.Status.Ingress {
+		a := Admission{Router: ingress.RouterName}
+		for _, c := range ingress.Conditions {
+			if c.Type != routev1.RouteAdmitted {
+				continue
+			}
+			a.Admitted = c.Status == corev1.ConditionTrue
+			a.Reason = c.Reason
+		}
+		admissions = append(admissions, a)
+	}
+	return
+}
+
+// Admitted checks if at least one router admitted the Route
+func (r *Route) Admitted() bool {
+	for _, a := range r.Admissions() {
+		if a.Admitted {
+			return true
+		}
+	}
+	return false
+}
+
+// routers returns the admitting routers if router probing is enabled for the
+// Route
+func (r *Route) routers() (routers []RouterInfo) {
+	enabled := false
+	if r.watcher != nil {
+		enabled = r.watcher.probeRouters
+	}
+	if apr, ok := r.GetAnnotations()["thobits.com/ormon-probe-routers"]; ok {
+		enabled, _ = strconv.ParseBool(apr)
+	}
+	if !enabled {
+		return nil
+	}
+	return r.admittedRouters()
+}
+
+// admittedRouters returns the routers from status.ingress that admitted the
+// Route, routers without a canonical hostname are skipped
+func (r *Route) admittedRouters() (routers []RouterInfo) {
+	admissions := r.Admissions()
+	for i, ingress := range r.Status.Ingress {
+		if ingress.RouterCanonicalHostname == "" || !admissions[i].Admitted {
+			continue
+		}
+		routers = append(routers, RouterInfo{
+			Name:              ingress.RouterName,
+			CanonicalHostname: ingress.RouterCanonicalHostname,
+		})
+	}
+	return
+}
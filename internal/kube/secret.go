@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	secretCacheTTL = time.Minute
+
+	// secretOptInLabel must be set to "true" on every Secret ormon may read
+	secretOptInLabel = "thobits.com/ormon-secret"
+)
+
+// newCoreClient creates a REST client for the core api group, used to read
+// Secrets
+func newCoreClient(config *rest.Config) (*rest.RESTClient, error) {
+	scheme := runtime.NewScheme()
+	err := corev1.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	c := *config
+	c.GroupVersion = &corev1.SchemeGroupVersion
+	c.APIPath = "/api"
+	c.NegotiatedSerializer = serializer.NewCodecFactory(scheme).WithoutConversion()
+	if c.UserAgent == "" {
+		c.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	return rest.RESTClientFor(&c)
+}
+
+// Secret reads a Secret, results are cached for a minute. Only Secrets that
+// opted in with the thobits.com/ormon-secret label are returned, so Route
+// authors can not send arbitrary Secrets of their namespace to a host.
+func (w *Watcher) Secret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	key := fmt.Sprintf("%s/%s", namespace, name)
+	if s, ok := w.secrets.Get(key); ok {
+		return optedIn(s.(*corev1.Secret))
+	}
+	s := &corev1.Secret{}
+	err := w.coreClient.Get().
+		Namespace(namespace).
+		Resource("secrets").
+		Name(name).
+		Do(ctx).
+		Into(s)
+	if err != nil {
+		return nil, err
+	}
+	w.secrets.Add(key, s, secretCacheTTL)
+	return optedIn(s)
+}
+
+// optedIn returns s if it carries the opt-in label
+func optedIn(s *corev1.Secret) (*corev1.Secret, error) {
+	if s.Labels[secretOptInLabel] != "true" {
+		return nil, fmt.Errorf("secret %s/%s is missing the label %s=true", s.Namespace, s.Name, secretOptInLabel)
+	}
+	return s, nil
+}
+
+// secretValue returns a single key of a Secret
+func secretValue(s *corev1.Secret, key string) (string, error) {
+	v, ok := s.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", s.Namespace, s.Name, key)
+	}
+	return string(v), nil
+}
+
+// authenticate injects the credentials from the auth Secret into req, the
+// credentials must never be logged
+func (r *Route) authenticate(ctx context.Context, req *http.Request, pi *ProbeInfo) error {
+	if pi.AuthSecret == "" {
+		return nil
+	}
+	if r.watcher == nil {
+		return fmt.Errorf("no client to read secret %s/%s", r.Namespace, pi.AuthSecret)
+	}
+	s, err := r.watcher.Secret(ctx, r.Namespace, pi.AuthSecret)
+	if err != nil {
+		return err
+	}
+	switch pi.AuthType {
+	case "basic":
+		username, err := secretValue(s, corev1.BasicAuthUsernameKey)
+		if err != nil {
+			return err
+		}
+		password, err := secretValue(s, corev1.BasicAuthPasswordKey)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(username, password)
+	case "bearer":
+		token, err := secretValue(s, "token")
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "header":
+		value, err := secretValue(s, "value")
+		if err != nil {
+			return err
+		}
+		req.Header.Set(pi.AuthHeader, value)
+	default:
+		return fmt.Errorf("unknown auth type %q", pi.AuthType)
+	}
+	return nil
+}
+
+// stripCredentials removes a custom auth header when a redirect leaves the
+// original host. net/http only strips Authorization, Cookie and
+// WWW-Authenticate itself.
+func (pi *ProbeInfo) stripCredentials(req *http.Request, via []*http.Request) {
+	if pi.AuthSecret == "" || pi.AuthType != "header" || len(via) == 0 {
+		return
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del(pi.AuthHeader)
+	}
+}
@@ -0,0 +1,224 @@
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TLSInfo describes the negotiated tls connection and the served certificate
+type TLSInfo struct {
+	Issuer      string
+	SubjectCN   string
+	Serial      string
+	SANs        int
+	Version     string
+	CipherSuite string
+	ALPN        string
+}
+
+// clientCertRejectedAlerts are the tls alerts a server sends if it does not
+// accept the client certificate
+var clientCertRejectedAlerts = []string{
+	"bad certificate",
+	"unsupported certificate",
+	"certificate revoked",
+	"certificate expired",
+	"certificate unknown",
+	"unknown certificate authority",
+	"certificate required",
+}
+
+// loadClientCertificate loads a PEM encoded client certificate and key from
+// disk, returns nil if no certificate is configured
+func loadClientCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// loadCABundle loads PEM encoded ca certificates from disk, returns nil if no
+// bundle is configured
+func loadCABundle(file string) ([]byte, error) {
+	if file == "" {
+		return nil, nil
+	}
+	bundle, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return bundle, nil
+}
+
+// clientCertificate returns the client certificate for the probe, either
+// from the Route's client cert Secret or the target's default
+func (r *Route) clientCertificate(ctx context.Context, pi *ProbeInfo) (*tls.Certificate, error) {
+	if r.watcher == nil {
+		return nil, nil
+	}
+	if pi.ClientCertSecret == "" {
+		return r.watcher.clientCert, nil
+	}
+	s, err := r.watcher.Secret(ctx, r.Namespace, pi.ClientCertSecret)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := secretValue(s, corev1.TLSCertKey)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := secretValue(s, corev1.TLSPrivateKeyKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: %s", s.Namespace, s.Name, err)
+	}
+	return &cert, nil
+}
+
+// tlsConfig creates the tls.Config used by the probe. The builtin
+// verification is disabled to still measure timings for broken
+// certificates, verification errors are recorded in m instead.
+func (r *Route) tlsConfig(ctx context.Context, m *RequestMetrics) (*tls.Config, error) {
+	c := &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if m.PeerCertificate == nil && len(cs.PeerCertificates) > 0 {
+				// only keep the leaf of the first connection, later
+				// connections are redirects
+				m.PeerCertificate = cs.PeerCertificates[0]
+			}
+			err := r.verifyCertificates(cs)
+			if err != nil && !m.SSLVerifyErr {
+				m.SSLVerifyErr = true
+				m.SSLVerifyReason = sslVerifyReason(err)
+				logrus.Errorf("%s %s %s", err, m.Cluster, m.URL())
+			}
+			return nil
+		},
+	}
+	cert, err := r.clientCertificate(ctx, m.ProbeInfo)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		// always present the certificate, even if the server does not list
+		// its issuer as acceptable
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return c, nil
+}
+
+// verifyCertificates verifies the peer certificates of a connection
+func (r *Route) verifyCertificates(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificates")
+	}
+	return r.verifyChain(cs.PeerCertificates[0], cs.PeerCertificates[1:], cs.ServerName)
+}
+
+// verifyChain verifies leaf against the system pool, the target's ca bundle
+// and the Route's ca certificate, the hostname is only checked if dnsName is
+// not empty
+func (r *Route) verifyChain(leaf *x509.Certificate, intermediates []*x509.Certificate, dnsName string) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if r.watcher != nil && r.watcher.caBundle != nil {
+		roots.AppendCertsFromPEM(r.watcher.caBundle)
+	}
+	if r.Spec.TLS != nil && r.Spec.TLS.CACertificate != "" {
+		roots.AppendCertsFromPEM([]byte(r.Spec.TLS.CACertificate))
+	}
+	intermediatePool := x509.NewCertPool()
+	for _, c := range intermediates {
+		intermediatePool.AddCert(c)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediatePool,
+	})
+	return err
+}
+
+// sslVerifyReason maps a verification error to a short reason
+func sslVerifyReason(err error) string {
+	switch e := err.(type) {
+	case x509.CertificateInvalidError:
+		if e.Reason == x509.Expired {
+			return "expired"
+		}
+		return "invalid"
+	case x509.UnknownAuthorityError:
+		return "unknown_authority"
+	case x509.HostnameError:
+		return "hostname_mismatch"
+	}
+	return "other"
+}
+
+// newTLSInfo extracts the TLSInfo from a tls.ConnectionState
+func newTLSInfo(cs *tls.ConnectionState) *TLSInfo {
+	ti := &TLSInfo{
+		Version:     tlsVersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:        cs.NegotiatedProtocol,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		leaf := cs.PeerCertificates[0]
+		ti.Issuer = leaf.Issuer.String()
+		ti.SubjectCN = leaf.Subject.CommonName
+		ti.Serial = leaf.SerialNumber.Text(16)
+		ti.SANs = len(leaf.DNSNames) + len(leaf.IPAddresses) +
+			len(leaf.EmailAddresses) + len(leaf.URIs)
+	}
+	return ti
+}
+
+// tlsVersionName returns the name of a tls version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// isClientCertRejected checks if err is caused by the server rejecting the
+// client certificate
+func isClientCertRejected(err error) bool {
+	msg := err.Error()
+	for _, alert := range clientCertRejectedAlerts {
+		if strings.Contains(msg, "remote error: tls: "+alert) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newTransport creates a dedicated http.Transport for a single probe, so
+// connections are never reused and every probe measures a fresh connection.
+// If a RouterAddress is set, connections to the Route's host are dialed to
+// the router instead, keeping the Host header and SNI. If an IPVersion is
+// set, only addresses of this version are dialed, also through a proxy by
+// tunneling to a resolved address of this version. Hosts that cannot be
+// resolved locally are left to the proxy. Hostnames are resolved with the
+// dns servers of the target. If a ProxyURL is set, connections are
+// tunneled through the proxy and tunneled is called once the tunnel is
+// established.
+func (r *Route) newTransport(pi *ProbeInfo, tlsConfig *tls.Config, tunneled func()) *http.Transport {
+	connectTimeout := 30 * time.Second
+	if pi.ConnectTimeout > 0 {
+		connectTimeout = pi.ConnectTimeout
+	}
+	tlsHandshakeTimeout := 10 * time.Second
+	if pi.TLSHandshakeTimeout > 0 {
+		tlsHandshakeTimeout = pi.TLSHandshakeTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+		Resolver:  r.dnsClient().resolver(),
+	}
+	routeHost := hostname(pi.Host)
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if pi.RouterAddress != "" {
+			host, port, err := net.SplitHostPort(addr)
+			if err == nil && host == routeHost {
+				addr = net.JoinHostPort(pi.RouterAddress, port)
+			}
+		}
+		if pi.IPVersion != "" {
+			network = "tcp" + pi.IPVersion
+		}
+		if pi.ProxyURL == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if pi.IPVersion != "" {
+			// the proxy would pick the address family itself
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := dialer.Resolver.LookupIP(ctx, "ip"+pi.IPVersion, host)
+			if err == nil && len(ips) > 0 {
+				addr = net.JoinHostPort(ips[0].String(), port)
+			}
+		}
+		conn, err := dialProxy(ctx, dialer, pi.ProxyURL, network, addr)
+		if err == nil && tunneled != nil {
+			tunneled()
+		}
+		return conn, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if pi.ProxyURL != nil {
+		// proxying is handled by the dialer
+		proxy = nil
+	}
+	return &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dial,
+		ForceAttemptHTTP2:   true,
+		DisableKeepAlives:   true,
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
+		TLSClientConfig:     tlsConfig,
+	}
+}
+
+// skipIPVersion reports whether the probe over pi.IPVersion is dropped as
+// the host only has addresses of the other ip version. If the host has no
+// addresses at all only the ipv4 probe is kept, so the failure is still
+// reported once, through a proxy this single probe lets the proxy resolve
+// the host.
+func (r *Route) skipIPVersion(ctx context.Context, pi *ProbeInfo) bool {
+	if pi.IPVersion == "" {
+		return false
+	}
+	found, err := r.hasAddress(ctx, pi, pi.IPVersion)
+	if found || err != nil {
+		// lookup failures are surfaced by the probe itself
+		return false
+	}
+	other := "4"
+	if pi.IPVersion == "4" {
+		other = "6"
+	}
+	found, err = r.hasAddress(ctx, pi, other)
+	if found && err == nil {
+		return true
+	}
+	return pi.IPVersion != "4"
+}
+
+// hasAddress reports whether the host dialed for pi has an address of the
+// ip version. A missing record is no error.
+func (r *Route) hasAddress(ctx context.Context, pi *ProbeInfo, version string) (bool, error) {
+	host := hostname(pi.Host)
+	if pi.RouterAddress != "" {
+		host = pi.RouterAddress
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return (ip.To4() != nil) == (version == "4"), nil
+	}
+	ips, err := r.dnsClient().resolver().LookupIP(ctx, "ip"+version, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(ips) > 0, nil
+}
@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// VersionRule captures the deployed version from a response header, a
+	// JSONPath or a body regex
+	VersionRule struct {
+		Header string
+		Path   string
+		Regex  *regexp.Regexp
+	}
+
+	// HostVersions lists the versions served for a host across all clusters
+	HostVersions struct {
+		Host     string       `json:"host"`
+		Skew     bool         `json:"skew"`
+		Versions []string     `json:"versions"`
+		Routes   []VersionRef `json:"routes"`
+	}
+
+	// VersionRef references the version served by a Route in a cluster
+	VersionRef struct {
+		RouteRef
+		Router  string `json:"router,omitempty"`
+		Version string `json:"version"`
+	}
+)
+
+// parseVersionRule parses a version rule: `{.jsonpath}` reads the body as
+// json, `~ regex` matches the body and `Header` or `Header ~ regex` reads a
+// response header. For regexes the first capture group is used if present.
+func (r *Route) parseVersionRule(a string) *VersionRule {
+	a = strings.TrimSpace(a)
+	vr := &VersionRule{}
+	var err error
+	switch {
+	case strings.HasPrefix(a, "{"):
+		var rest string
+		vr.Path, rest, err = splitJSONPath(a)
+		if err == nil && rest != "" {
+			err = fmt.Errorf("unexpected %q", rest)
+		}
+	default:
+		expr := ""
+		vr.Header = a
+		if i := strings.Index(a, "~"); i >= 0 {
+			vr.Header = strings.TrimSpace(a[:i])
+			expr = strings.TrimSpace(a[i+1:])
+			vr.Regex, err = regexp.Compile(expr)
+			if err == nil && expr == "" {
+				err = fmt.Errorf("empty regex")
+			}
+		}
+		if err == nil && vr.Header == "" && vr.Regex == nil {
+			err = fmt.Errorf("empty rule")
+		}
+	}
+	if err != nil {
+		logrus.Warnf("invalid version rule %q %s %s %s/%s", a, err, r.ClusterName, r.Namespace, r.Name)
+		return nil
+	}
+	return vr
+}
+
+// capture extracts the version from the response
+func (vr *VersionRule) capture(header http.Header, body []byte, data interface{}) (string, error) {
+	if vr.Path != "" {
+		v, err := jsonPathValue(vr.Path, data)
+		if err != nil {
+			return "", err
+		}
+		return jsonString(v), nil
+	}
+	value := body
+	if vr.Header != "" {
+		if _, ok := header[http.CanonicalHeaderKey(vr.Header)]; !ok {
+			return "", fmt.Errorf("version header %s missing", vr.Header)
+		}
+		value = []byte(header.Get(vr.Header))
+	}
+	if vr.Regex == nil {
+		return string(value), nil
+	}
+	match := vr.Regex.FindSubmatch(value)
+	if match == nil {
+		return "", fmt.Errorf("version regex does not match")
+	}
+	if len(match) > 1 {
+		return string(match[1]), nil
+	}
+	return string(match[0]), nil
+}
+
+// captureVersion returns the version served by the probed Route or an
+// empty string. JSONPath rules are skipped if the body is no valid json.
+func (pi *ProbeInfo) captureVersion(header http.Header, body []byte, data interface{}, parseErr bool) string {
+	if pi.VersionRule == nil || (pi.VersionRule.Path != "" && parseErr) {
+		return ""
+	}
+	version, err := pi.VersionRule.capture(header, body, data)
+	if err != nil {
+		logrus.Errorf("%s %s %s", err, pi.Cluster, pi.URL())
+		return ""
+	}
+	return strings.TrimSpace(version)
+}
+
+// FindHostVersions groups the captured versions of all probes by host and
+// flags hosts serving different versions
+func FindHostVersions(metrics []*RequestMetrics) (hosts []HostVersions) {
+	byHost := map[string]*HostVersions{}
+	seen := map[VersionRef]bool{}
+	for _, m := range metrics {
+		if m.Version == "" {
+			continue
+		}
+		ref := VersionRef{
+			RouteRef: RouteRef{
+				Cluster:   m.Cluster,
+				Namespace: m.Namespace,
+				Name:      m.Name,
+				UID:       m.UID,
+			},
+			Router:  m.Router,
+			Version: m.Version,
+		}
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		host := hostname(m.Host)
+		hv, ok := byHost[host]
+		if !ok {
+			hv = &HostVersions{Host: host}
+			byHost[host] = hv
+		}
+		hv.Routes = append(hv.Routes, ref)
+	}
+	hosts = []HostVersions{}
+	for _, hv := range byHost {
+		versions := map[string]bool{}
+		for _, ref := range hv.Routes {
+			if !versions[ref.Version] {
+				versions[ref.Version] = true
+				hv.Versions = append(hv.Versions, ref.Version)
+			}
+		}
+		sort.Strings(hv.Versions)
+		hv.Skew = len(hv.Versions) > 1
+		hosts = append(hosts, *hv)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Host < hosts[j].Host
+	})
+	return
+}
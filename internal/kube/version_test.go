@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+func TestParseVersionRule(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	tests := []struct {
+		rule   string
+		valid  bool
+		header string
+		path   string
+		regex  string
+	}{
+		{"X-App-Version", true, "X-App-Version", "", ""},
+		{"Server ~ myapp/(.+)", true, "Server", "", "myapp/(.+)"},
+		{"{.build.version}", true, "", "{.build.version}", ""},
+		{"~ version ([0-9.]+)", true, "", "", "version ([0-9.]+)"},
+		{"", false, "", "", ""},
+		{"~", false, "", "", ""},
+		{"Server ~", false, "", "", ""},
+		{"{.build.version", false, "", "", ""},
+		{"{.build.version} == 1", false, "", "", ""},
+		{"Server ~ (", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		vr := r.parseVersionRule(tt.rule)
+		if !tt.valid {
+			if vr != nil {
+				t.Errorf("%q: got %+v, want rejected", tt.rule, vr)
+			}
+			continue
+		}
+		if vr == nil {
+			t.Errorf("%q: got rejected", tt.rule)
+			continue
+		}
+		regex := ""
+		if vr.Regex != nil {
+			regex = vr.Regex.String()
+		}
+		if vr.Header != tt.header || vr.Path != tt.path || regex != tt.regex {
+			t.Errorf("%q: got %q %q %q, want %q %q %q", tt.rule, vr.Header, vr.Path, regex, tt.header, tt.path, tt.regex)
+		}
+	}
+}
+
+func TestCaptureVersion(t *testing.T) {
+	r := &Route{Route: &routev1.Route{}}
+	header := http.Header{}
+	header.Set("X-App-Version", " 1.2.3 ")
+	header.Set("Server", "myapp/2.0.0")
+	body := []byte(`{"build": {"version": "3.1.0", "number": 42}, "text": "running version 4.0"}`)
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rule     string
+		parseErr bool
+		version  string
+	}{
+		{"x-app-version", false, "1.2.3"},
+		{"Server ~ myapp/(.+)", false, "2.0.0"},
+		{"Server ~ other/(.+)", false, ""},
+		{"X-Missing", false, ""},
+		{"{.build.version}", false, "3.1.0"},
+		{"{.build.number}", false, "42"},
+		{"{.build.missing}", false, ""},
+		{"{.build.version}", true, ""},
+		{"~ version ([0-9.]+)", false, "4.0"},
+		{"~ version ([0-9.]+)", true, "4.0"},
+	}
+	for _, tt := range tests {
+		pi := &ProbeInfo{VersionRule: r.parseVersionRule(tt.rule)}
+		if version := pi.captureVersion(header, body, data, tt.parseErr); version != tt.version {
+			t.Errorf("%q: got %q, want %q", tt.rule, version, tt.version)
+		}
+	}
+}
+
+func TestFindHostVersions(t *testing.T) {
+	probe := func(cluster, host, router, version string) *RequestMetrics {
+		return &RequestMetrics{ProbeInfo: &ProbeInfo{
+			Cluster: cluster, Host: host, Router: router, Name: "app", Namespace: "shop", UID: cluster,
+		}, Version: version}
+	}
+	hosts := FindHostVersions([]*RequestMetrics{
+		probe("dev", "shop.example.com", "", "1.1"),
+		probe("prod", "shop.example.com", "", "1.0"),
+		// the same version over another ip version is listed once
+		probe("prod", "shop.example.com", "", "1.0"),
+		probe("prod", "api.example.com", "default", "2.0"),
+		probe("prod", "api.example.com", "sharded", "2.0"),
+		probe("prod", "static.example.com", "", ""),
+	})
+	if len(hosts) != 2 {
+		t.Fatalf("got %+v, want two hosts", hosts)
+	}
+	if hosts[0].Host != "api.example.com" || hosts[0].Skew || len(hosts[0].Routes) != 2 {
+		t.Errorf("got %+v, want api.example.com without skew via two routers", hosts[0])
+	}
+	if hosts[1].Host != "shop.example.com" || !hosts[1].Skew || len(hosts[1].Routes) != 2 {
+		t.Errorf("got %+v, want shop.example.com with skew", hosts[1])
+	}
+	if len(hosts[1].Versions) != 2 || hosts[1].Versions[0] != "1.0" {
+		t.Errorf("got versions %v, want sorted 1.0 and 1.1", hosts[1].Versions)
+	}
+}
@@ -2,6 +2,9 @@ package kube
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
 	"net/url"
 	"regexp"
 	"time"
@@ -10,6 +13,7 @@ import (
 	csroutev1 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
@@ -24,9 +28,20 @@ type (
 
 	// Config to create a watcher
 	Config struct {
-		Kubeconfig          string `yaml:"kubeconfig"`
-		NamespaceBlackRegex string `yaml:"namespace_blacklist_regex"`
-		Labels              Labels `yaml:"labels"`
+		Kubeconfig          string      `yaml:"kubeconfig"`
+		NamespaceBlackRegex string      `yaml:"namespace_blacklist_regex"`
+		Labels              Labels      `yaml:"labels"`
+		ClientCert          string      `yaml:"client_cert"`
+		ClientKey           string      `yaml:"client_key"`
+		CABundle            string      `yaml:"ca_bundle"`
+		DefaultRouterCert   string      `yaml:"default_router_cert"`
+		WildcardSubdomains  []string    `yaml:"wildcard_subdomains"`
+		ProbeRouters        bool        `yaml:"probe_routers"`
+		ExpectedCIDRs       []string    `yaml:"expected_cidrs"`
+		DNS                 DNSConfig   `yaml:"dns"`
+		DualStack           bool        `yaml:"dual_stack"`
+		Proxy               ProxyConfig `yaml:"proxy"`
+		ErrorPageSignatures []string    `yaml:"error_page_signatures"`
 	}
 
 	// Watcher watcher monitors a cluster for route events
@@ -34,6 +49,18 @@ type (
 		kubeconfig          string
 		config              *rest.Config
 		clientset           *csroutev1.RouteV1Client
+		coreClient          *rest.RESTClient
+		secrets             *utilcache.LRUExpireCache
+		clientCert          *tls.Certificate
+		caBundle            []byte
+		defaultRouterCerts  []*x509.Certificate
+		wildcardSubdomains  []string
+		probeRouters        bool
+		expectedCIDRs       []*net.IPNet
+		dns                 *dnsClient
+		dualStack           bool
+		proxy               *egressProxy
+		errorPageSignatures []*regexp.Regexp
 		cache               cache.Store
 		controller          cache.Controller
 		Labels              Labels
@@ -60,6 +87,10 @@ func NewWatcher(c Config) (w *Watcher, err error) {
 	if err != nil {
 		return
 	}
+	coreClient, err := newCoreClient(config)
+	if err != nil {
+		return
+	}
 	if c.NamespaceBlackRegex == "" {
 		c.NamespaceBlackRegex = "^$"
 	}
@@ -78,10 +109,51 @@ func NewWatcher(c Config) (w *Watcher, err error) {
 		return
 	}
 
+	clientCert, err := loadClientCertificate(c.ClientCert, c.ClientKey)
+	if err != nil {
+		return
+	}
+	caBundle, err := loadCABundle(c.CABundle)
+	if err != nil {
+		return
+	}
+	defaultRouterCerts, err := loadDefaultRouterCerts(c.DefaultRouterCert)
+	if err != nil {
+		return
+	}
+	expectedCIDRs, err := parseCIDRs(c.ExpectedCIDRs)
+	if err != nil {
+		return
+	}
+	dns, err := newDNSClient(c.DNS)
+	if err != nil {
+		return
+	}
+	proxy, err := newEgressProxy(c.Proxy)
+	if err != nil {
+		return
+	}
+	errorPageSignatures, err := parseErrorPageSignatures(c.ErrorPageSignatures)
+	if err != nil {
+		return
+	}
+
 	return &Watcher{
 		kubeconfig:          c.Kubeconfig,
 		config:              config,
 		clientset:           clientset,
+		coreClient:          coreClient,
+		secrets:             utilcache.NewLRUExpireCache(1024),
+		clientCert:          clientCert,
+		caBundle:            caBundle,
+		defaultRouterCerts:  defaultRouterCerts,
+		wildcardSubdomains:  c.WildcardSubdomains,
+		probeRouters:        c.ProbeRouters,
+		expectedCIDRs:       expectedCIDRs,
+		dns:                 dns,
+		dualStack:           c.DualStack,
+		proxy:               proxy,
+		errorPageSignatures: errorPageSignatures,
 		cache:               cache,
 		controller:          controller,
 		Labels:              c.Labels,
@@ -112,7 +184,7 @@ func (w *Watcher) List() (routes []*Route) {
 	host, _ := url.Parse(w.config.Host)
 	for _, ri := range routeInterfaces {
 		rv1 := ri.(*routev1.Route)
-		r := Route{rv1, host.Host}
+		r := Route{Route: rv1, ClusterName: host.Host, watcher: w}
 		if w.validRoute(&r) {
 			routes = append(routes, &r)
 		}
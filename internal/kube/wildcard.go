@@ -0,0 +1,49 @@
+package kube
+
+import (
+	"fmt"
+	"strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+// wildcardHosts returns the pattern and the sample hosts to probe for
+// Routes with wildcardPolicy Subdomain, the pattern is empty for other
+// Routes. The sample subdomains are taken from the annotation, the target's
+// config or generated from the Route's uid if none are left after removing
+// empty and duplicate entries.
+func (r *Route) wildcardHosts() (pattern string, hosts []string) {
+	if r.Spec.WildcardPolicy != routev1.WildcardPolicySubdomain {
+		return "", nil
+	}
+	domain := r.Spec.Host
+	if i := strings.Index(domain, "."); i >= 0 {
+		domain = domain[i+1:]
+	}
+	pattern = fmt.Sprintf("*.%s", domain)
+
+	subdomains := []string{}
+	if r.watcher != nil {
+		subdomains = r.watcher.wildcardSubdomains
+	}
+	if aws, ok := r.GetAnnotations()["thobits.com/ormon-wildcard-subdomains"]; ok {
+		subdomains = strings.Split(aws, ",")
+	}
+	seen := map[string]bool{}
+	for _, s := range subdomains {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		hosts = append(hosts, fmt.Sprintf("%s.%s", s, domain))
+	}
+	if len(hosts) == 0 {
+		uid := strings.ReplaceAll(string(r.GetUID()), "-", "")
+		if len(uid) > 8 {
+			uid = uid[:8]
+		}
+		hosts = []string{fmt.Sprintf("ormon-%s.%s", uid, domain)}
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+)
+
+// conflictsHandler lists all Routes sharing the same host and path
+func (m *Monitor) conflictsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(m.mw.Conflicts())
+	if err != nil {
+		logrus.Errorf("unable to encode conflicts: %s", err)
+	}
+}
+
+// versionsHandler lists the versions served per host across all clusters
+func (m *Monitor) versionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(kube.FindHostVersions(m.scheduler.Results()))
+	if err != nil {
+		logrus.Errorf("unable to encode versions: %s", err)
+	}
+}
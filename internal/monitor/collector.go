@@ -1,56 +1,96 @@
 package monitor
 
 import (
-	"context"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+	"github.com/bitsbeats/openshift-route-monitor/internal/scheduler"
 )
 
 // Collector implements prometheus.Collector
 type Collector struct {
-	mw        *kube.MultiWatcher
-	descCache descMap
+	scheduler   *scheduler.Scheduler
+	descCache   descMap
+	versionSkew *prometheus.Desc
 }
 
-// NewCollector creates a prometheus.Collector that montors all Routes from mw
-func NewCollector(mw *kube.MultiWatcher) *Collector {
+// NewCollector creates a prometheus.Collector that exports the cached probe
+// results of s
+func NewCollector(s *scheduler.Scheduler) *Collector {
 	descCache := newDescMap(mapBuilder{
+		"last_probe_timestamp_seconds": {
+			"unix timestamp of the last probe",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				return float64(m.Probed.UnixNano()) / 1e9, []string{}
+			},
+			[]string{},
+		},
+		"probe_age_seconds": {
+			"seconds since the last probe",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				return time.Since(m.Probed).Seconds(), []string{}
+			},
+			[]string{},
+		},
 		"resolved_seconds": {
 			"time to resolve hostname",
 			func(m *kube.RequestMetrics) (float64, []string) {
 				return m.Resolved.Seconds(), []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"dns_cname_depth": {
+			"number of cnames until the host resolves",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				return float64(m.CNAMEDepth), []string{}
+			},
+			[]string{},
+		},
+		"dns_mismatch_error": {
+			"host does not resolve to the routers or expected cidrs",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.DNSMismatchErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
 		},
 		"connected_seconds": {
 			"time to open the connection",
 			func(m *kube.RequestMetrics) (float64, []string) { return m.Connected.Seconds(), []string{} },
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"proxy_tunnel_seconds": {
+			"time until the tunnel through the proxy was established",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				return m.ProxyTunnel.Seconds(), []string{}
+			},
+			[]string{},
 		},
 		"wrote_request_seconds": {
 			"time until the full request was sent",
 			func(m *kube.RequestMetrics) (float64, []string) {
 				return m.WroteRequest.Seconds(), []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"read_first_byte_seconds": {
 			"time until first byte was read",
 			func(m *kube.RequestMetrics) (float64, []string) {
 				return m.ReadFirstByte.Seconds(), []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"read_body_seconds": {
 			"time until full body was read",
 			func(m *kube.RequestMetrics) (float64, []string) {
 				return m.ReadBody.Seconds(), []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"ssl_exires_seconds": {
 			"seconds until the ssl expires",
@@ -62,14 +102,70 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				untilExpire := time.Until(m.Expires).Seconds()
 				return untilExpire, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"tls_handshake_seconds": {
+			"duration of the tls handshake",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				return m.TLSHandshake.Seconds(), []string{}
+			},
+			[]string{},
+		},
+		"ssl_cert_info": {
+			"served certificate and negotiated tls parameters",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.TLS == nil {
+					// no tls connection
+					return 0, []string{"", "", "", "", "", "", ""}
+				}
+				return 1, []string{
+					m.TLS.Issuer,
+					m.TLS.SubjectCN,
+					m.TLS.Serial,
+					strconv.Itoa(m.TLS.SANs),
+					m.TLS.Version,
+					m.TLS.CipherSuite,
+					m.TLS.ALPN,
+				}
+			},
+			[]string{"issuer", "subject_cn", "serial", "sans", "tls_version", "cipher_suite", "alpn"},
+		},
+		"ssl_verify_error": {
+			"certificate verification failed",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.SSLVerifyErr {
+					return 1, []string{m.SSLVerifyReason}
+				}
+				return 0, []string{""}
+			},
+			[]string{"reason"},
+		},
+		"ssl_cert_mismatch": {
+			"served certificate does not match spec.tls.certificate",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.SSLCertMismatchErr {
+					return 1, []string{strconv.FormatBool(m.DefaultRouterCert)}
+				}
+				return 0, []string{"false"}
+			},
+			[]string{"default_router_cert"},
+		},
+		"insecure_policy_error": {
+			"plain http behavior violates spec.tls.insecureEdgeTerminationPolicy",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.InsecurePolicyErr {
+					return 1, []string{m.InsecurePolicy}
+				}
+				return 0, []string{m.InsecurePolicy}
+			},
+			[]string{"policy"},
 		},
 		"redirect_count": {
 			"number of http redirects",
 			func(m *kube.RequestMetrics) (float64, []string) {
 				return float64(m.RedirectCount), []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"invalid_request_error": {
 			"errors during request",
@@ -79,7 +175,47 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"not_admitted_error": {
+			"route is not admitted by any router and was not probed",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.NotAdmittedErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
+		},
+		"auth_error": {
+			"errors reading the auth secret",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.AuthErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
+		},
+		"client_cert_error": {
+			"errors loading the client certificate",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.ClientCertErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
+		},
+		"client_cert_rejected_error": {
+			"client certificate rejected during tls handshake",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.ClientCertRejectedErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
 		},
 		"connection_error": {
 			"errors during connection opening",
@@ -89,7 +225,17 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"proxy_error": {
+			"errors connecting through the proxy",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.ProxyErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
 		},
 		"body_download_error": {
 			"errors during body download",
@@ -99,7 +245,7 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"invalid_statuscode_error": {
 			"invalid statuscode",
@@ -109,7 +255,7 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
 		},
 		"invalid_body_regex_error": {
 			"invalid regex",
@@ -119,7 +265,37 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+		"router_no_backend_error": {
+			"router served its error page as the route has no ready endpoints",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.RouterNoBackendErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
+		},
+		"json_parse_error": {
+			"body is not valid json",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.JSONParseErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
+		},
+		"health_error": {
+			"health check status is not up",
+			func(m *kube.RequestMetrics) (float64, []string) {
+				if m.HealthErr {
+					return 1, []string{}
+				}
+				return 0, []string{}
+			},
+			[]string{},
 		},
 		"invalid_body_error": {
 			"invalid body",
@@ -129,12 +305,79 @@ func NewCollector(mw *kube.MultiWatcher) *Collector {
 				}
 				return 0, []string{}
 			},
-			[]string{"host", "path", "ssl", "cluster", "uid", "namespace", "name"},
+			[]string{},
+		},
+	})
+	descCache.addMulti(multiMapBuilder{
+		"invalid_header_error": {
+			"response header assertion failed",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				for _, r := range m.HeaderResults {
+					values = append(values, labeledValue{boolToFloat(r.Failed), []string{r.Name}})
+				}
+				return
+			},
+			[]string{"header"},
+		},
+		"body_assertion_error": {
+			"named body assertion failed",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				for _, r := range m.BodyResults {
+					values = append(values, labeledValue{boolToFloat(r.Failed), []string{r.Name}})
+				}
+				return
+			},
+			[]string{"assertion"},
+		},
+		"invalid_json_error": {
+			"json assertion failed",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				for _, r := range m.JSONResults {
+					values = append(values, labeledValue{boolToFloat(r.Failed), []string{r.Name}})
+				}
+				return
+			},
+			[]string{"assertion"},
+		},
+		"extracted_value": {
+			"numeric value extracted from the response",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				for _, e := range m.Extracted {
+					values = append(values, labeledValue{e.Value, []string{e.Key}})
+				}
+				return
+			},
+			[]string{"key"},
+		},
+		"health_component_up": {
+			"health check component is up",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				for _, c := range m.Components {
+					values = append(values, labeledValue{boolToFloat(c.Healthy), []string{c.Name, c.Status}})
+				}
+				return
+			},
+			[]string{"component", "status"},
+		},
+		"route_version_info": {
+			"version served by the route",
+			func(m *kube.RequestMetrics) (values []labeledValue) {
+				if m.Version != "" {
+					values = append(values, labeledValue{1, []string{m.Version}})
+				}
+				return
+			},
+			[]string{"version"},
 		},
 	})
 	return &Collector{
-		mw:        mw,
+		scheduler: s,
 		descCache: descCache,
+		versionSkew: prometheus.NewDesc(
+			"ormon_version_skew",
+			"number of different versions served for the host across all clusters",
+			[]string{"host"}, prometheus.Labels{},
+		),
 	}
 }
 
@@ -142,34 +385,22 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	for _, v := range c.descCache {
 		ch <- v.desc
 	}
+	ch <- c.versionSkew
 }
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
-	wg := sync.WaitGroup{}
-	routes := c.mw.List()
-	wg.Add(len(routes))
-	for _, r := range routes {
-		go func(r *kube.Route) {
-			defer wg.Done()
-			c.check(r, ch)
-		}(r)
-	}
-	wg.Wait()
-}
-
-func (c *Collector) check(r *kube.Route, ch chan<- prometheus.Metric) {
-	ctx, cancel := context.WithTimeout(context.Background(), 9*time.Second)
-	defer cancel()
-
-	rm := r.Probe(ctx)
-	if rm == nil {
-		return
-	}
-	for _, v := range c.descCache {
-		pm, err := v.getPromConstMetric(rm)
-		if err != nil {
-			continue
+	results := c.scheduler.Results()
+	for _, rm := range results {
+		for _, v := range c.descCache {
+			for _, pm := range v.getPromConstMetrics(rm) {
+				ch <- pm
+			}
 		}
-		ch <- pm
+	}
+	for _, hv := range kube.FindHostVersions(results) {
+		ch <- prometheus.MustNewConstMetric(
+			c.versionSkew, prometheus.GaugeValue,
+			float64(len(hv.Versions)), hv.Host,
+		)
 	}
 }
@@ -8,6 +8,10 @@ import (
 	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
 )
 
+// probeLabels are the labels of every probe metric, followed by the
+// additional labels of the metric
+var probeLabels = []string{"host", "path", "ssl", "cluster", "uid", "namespace", "name", "wildcard", "router", "ip_version"}
+
 type (
 	// mapBuilder is a
 	mapBuilder map[string]struct {
@@ -16,10 +20,24 @@ type (
 		labels      []string
 	}
 
-	valueGetter func(*kube.RequestMetrics) (value float64, additionalLabels []string)
-	descValue   struct {
-		desc        *prometheus.Desc
-		valueGetter valueGetter
+	// multiMapBuilder is a mapBuilder for metrics with multiple series per
+	// RequestMetrics
+	multiMapBuilder map[string]struct {
+		descStr      string
+		valuesGetter valuesGetter
+		labels       []string
+	}
+
+	valueGetter  func(*kube.RequestMetrics) (value float64, additionalLabels []string)
+	valuesGetter func(*kube.RequestMetrics) []labeledValue
+	labeledValue struct {
+		value            float64
+		additionalLabels []string
+	}
+	descValue struct {
+		desc         *prometheus.Desc
+		valueGetter  valueGetter
+		valuesGetter valuesGetter
 	}
 
 	// descMap is a prometheus desc storage and metric generator
@@ -31,18 +49,49 @@ func newDescMap(mb mapBuilder) (dm descMap) {
 	dm = descMap{}
 	for k, v := range mb {
 		pk := fmt.Sprintf("ormon_%s", k)
+		labels := append(append([]string{}, probeLabels...), v.labels...)
 		dm[k] = &descValue{
-			desc:        prometheus.NewDesc(pk, v.descStr, v.labels, prometheus.Labels{}),
+			desc:        prometheus.NewDesc(pk, v.descStr, labels, prometheus.Labels{}),
 			valueGetter: v.valueGetter,
 		}
 	}
 	return
 }
 
-// getPromConstMetric generates the matching prometheus.Metric using
-// the confgured valueGetter and the stored *prometheus.Desc
-func (dv descValue) getPromConstMetric(rm *kube.RequestMetrics) (pm prometheus.Metric, err error) {
-	value, labels := dv.valueGetter(rm)
+// addMulti adds the metrics with multiple series per RequestMetrics
+func (dm descMap) addMulti(mmb multiMapBuilder) {
+	for k, v := range mmb {
+		pk := fmt.Sprintf("ormon_%s", k)
+		labels := append(append([]string{}, probeLabels...), v.labels...)
+		dm[k] = &descValue{
+			desc:         prometheus.NewDesc(pk, v.descStr, labels, prometheus.Labels{}),
+			valuesGetter: v.valuesGetter,
+		}
+	}
+}
+
+// getPromConstMetrics generates the matching prometheus.Metrics using the
+// configured valueGetter or valuesGetter and the stored *prometheus.Desc
+func (dv descValue) getPromConstMetrics(rm *kube.RequestMetrics) (pms []prometheus.Metric) {
+	values := []labeledValue{}
+	if dv.valuesGetter != nil {
+		values = dv.valuesGetter(rm)
+	} else {
+		value, labels := dv.valueGetter(rm)
+		values = append(values, labeledValue{value, labels})
+	}
+	for _, v := range values {
+		pm, err := dv.getPromConstMetric(rm, v.value, v.additionalLabels)
+		if err != nil {
+			continue
+		}
+		pms = append(pms, pm)
+	}
+	return
+}
+
+// getPromConstMetric generates a single prometheus.Metric
+func (dv descValue) getPromConstMetric(rm *kube.RequestMetrics, value float64, labels []string) (pm prometheus.Metric, err error) {
 	labels = append([]string{
 		rm.Host,
 		rm.Path,
@@ -51,6 +100,9 @@ func (dv descValue) getPromConstMetric(rm *kube.RequestMetrics) (pm prometheus.M
 		rm.UID,
 		rm.Namespace,
 		rm.Name,
+		rm.Wildcard,
+		rm.Router,
+		rm.IPVersion,
 	}, labels...)
 	return prometheus.NewConstMetric(dv.desc, prometheus.GaugeValue, value, labels...)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+	"github.com/bitsbeats/openshift-route-monitor/internal/scheduler"
 )
 
 type (
@@ -17,27 +18,35 @@ type (
 		Listen string `yaml:"listen"`
 	}
 
-	// Monitor periodically checks routes
+	// Monitor exports the route checks
 	Monitor struct {
-		config Config
+		config    Config
+		mw        *kube.MultiWatcher
+		scheduler *scheduler.Scheduler
 	}
 )
 
 // New creates a new Monitor from Config
-func New(c Config, mw *kube.MultiWatcher) (m *Monitor, err error) {
+func New(c Config, mw *kube.MultiWatcher, s *scheduler.Scheduler) (m *Monitor, err error) {
 	if c.Listen == "" {
 		c.Listen = ":9142"
 	}
-	err = prometheus.Register(NewCollector(mw))
+	err = prometheus.Register(NewCollector(s))
 	if err != nil {
 		return nil, err
 	}
-	return &Monitor{config: c}, nil
+	err = prometheus.Register(NewRouteCollector(mw))
+	if err != nil {
+		return nil, err
+	}
+	return &Monitor{config: c, mw: mw, scheduler: s}, nil
 }
 
 // Run starts the metric server
 func (m *Monitor) Run(ctx context.Context, errs chan<- error) {
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/api/conflicts", m.conflictsHandler)
+	http.HandleFunc("/api/versions", m.versionsHandler)
 	go func() {
 		logrus.Infof("listening on %s", m.config.Listen)
 		s := http.Server{Addr: m.config.Listen}
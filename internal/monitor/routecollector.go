@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+)
+
+var routeLabels = []string{"host", "path", "cluster", "uid", "namespace", "name"}
+
+// RouteCollector implements prometheus.Collector for metrics that are
+// derived from the Route objects without probing
+type RouteCollector struct {
+	mw *kube.MultiWatcher
+
+	admitted        *prometheus.Desc
+	conflict        *prometheus.Desc
+	certExpires     *prometheus.Desc
+	certParseErr    *prometheus.Desc
+	certKeyMismatch *prometheus.Desc
+	certChainValid  *prometheus.Desc
+	certHostCovered *prometheus.Desc
+}
+
+// NewRouteCollector creates a prometheus.Collector that analyzes all Routes
+// from mw, including skipped Routes
+func NewRouteCollector(mw *kube.MultiWatcher) *RouteCollector {
+	return &RouteCollector{
+		mw: mw,
+
+		admitted: prometheus.NewDesc(
+			"ormon_route_admitted",
+			"route is admitted by the router",
+			append(routeLabels, "router", "reason"), prometheus.Labels{},
+		),
+		conflict: prometheus.NewDesc(
+			"ormon_route_conflict",
+			"number of other routes with the same host and path",
+			routeLabels, prometheus.Labels{},
+		),
+		certExpires: prometheus.NewDesc(
+			"ormon_route_cert_expires_seconds",
+			"seconds until the earliest certificate in spec.tls expires",
+			append(routeLabels, "certificate"), prometheus.Labels{},
+		),
+		certParseErr: prometheus.NewDesc(
+			"ormon_route_cert_parse_error",
+			"certificate in spec.tls can not be parsed",
+			routeLabels, prometheus.Labels{},
+		),
+		certKeyMismatch: prometheus.NewDesc(
+			"ormon_route_cert_key_mismatch",
+			"spec.tls.key does not match spec.tls.certificate",
+			routeLabels, prometheus.Labels{},
+		),
+		certChainValid: prometheus.NewDesc(
+			"ormon_route_cert_chain_valid",
+			"spec.tls.certificate has a valid chain",
+			routeLabels, prometheus.Labels{},
+		),
+		certHostCovered: prometheus.NewDesc(
+			"ormon_route_cert_host_covered",
+			"spec.tls.certificate is valid for spec.host",
+			routeLabels, prometheus.Labels{},
+		),
+	}
+}
+
+func (c *RouteCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.admitted
+	ch <- c.conflict
+	ch <- c.certExpires
+	ch <- c.certParseErr
+	ch <- c.certKeyMismatch
+	ch <- c.certChainValid
+	ch <- c.certHostCovered
+}
+
+func (c *RouteCollector) Collect(ch chan<- prometheus.Metric) {
+	routes := c.mw.List()
+	conflicts := map[string]int{}
+	for _, conflict := range kube.FindConflicts(routes) {
+		for _, ref := range conflict.Routes {
+			conflicts[ref.UID] = len(conflict.Routes) - 1
+		}
+	}
+	for _, r := range routes {
+		ch <- prometheus.MustNewConstMetric(
+			c.conflict, prometheus.GaugeValue,
+			float64(conflicts[string(r.GetUID())]), routeLabelValues(r)...,
+		)
+		c.collectAdmissions(r, ch)
+		c.collectCertificates(r, ch)
+	}
+}
+
+// collectAdmissions exports the admission state per router
+func (c *RouteCollector) collectAdmissions(r *kube.Route, ch chan<- prometheus.Metric) {
+	labels := routeLabelValues(r)
+	for _, a := range r.Admissions() {
+		ch <- prometheus.MustNewConstMetric(
+			c.admitted, prometheus.GaugeValue,
+			boolToFloat(a.Admitted), append(labels, a.Router, a.Reason)...,
+		)
+	}
+}
+
+// collectCertificates exports the static analysis of the Route's certificates
+func (c *RouteCollector) collectCertificates(r *kube.Route, ch chan<- prometheus.Metric) {
+	ca := r.AnalyzeCertificates()
+	if ca == nil {
+		return
+	}
+	labels := routeLabelValues(r)
+	for field, expires := range ca.Expires {
+		ch <- prometheus.MustNewConstMetric(
+			c.certExpires, prometheus.GaugeValue,
+			time.Until(expires).Seconds(), append(labels, field)...,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(c.certParseErr, prometheus.GaugeValue, boolToFloat(ca.ParseErr), labels...)
+	if !ca.Custom {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.certKeyMismatch, prometheus.GaugeValue, boolToFloat(ca.KeyMismatch), labels...)
+	ch <- prometheus.MustNewConstMetric(c.certChainValid, prometheus.GaugeValue, boolToFloat(ca.ChainValid), labels...)
+	ch <- prometheus.MustNewConstMetric(c.certHostCovered, prometheus.GaugeValue, boolToFloat(ca.HostCovered), labels...)
+}
+
+// routeLabelValues returns the values for routeLabels
+func routeLabelValues(r *kube.Route) []string {
+	return []string{r.Spec.Host, r.Spec.Path, r.ClusterName, string(r.GetUID()), r.Namespace, r.Name}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
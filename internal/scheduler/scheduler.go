@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+)
+
+type (
+	// Config holds the Scheduler configuration
+	Config struct {
+		Interval    time.Duration `yaml:"interval"`
+		Timeout     time.Duration `yaml:"timeout"`
+		Concurrency int           `yaml:"concurrency"`
+	}
+
+	// Scheduler periodically probes all Routes in the background and caches
+	// the latest result per Route
+	Scheduler struct {
+		config Config
+		mw     routeLister
+		sem    chan struct{}
+
+		mu      sync.RWMutex
+		entries map[string]*entry
+	}
+
+	// routeLister lists the Routes to probe
+	routeLister interface {
+		List() []*kube.Route
+	}
+
+	// entry is the cached state of a single Route
+	entry struct {
+		metrics []*kube.RequestMetrics
+		next    time.Time
+		running bool
+	}
+)
+
+// New creates a new Scheduler from Config
+func New(c Config, mw *kube.MultiWatcher) (s *Scheduler, err error) {
+	if c.Interval == 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 9 * time.Second
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = 10
+	}
+	if c.Interval < 0 {
+		return nil, fmt.Errorf("invalid interval %s", c.Interval)
+	}
+	if c.Timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout %s", c.Timeout)
+	}
+	if c.Concurrency < 0 {
+		return nil, fmt.Errorf("invalid concurrency %d", c.Concurrency)
+	}
+	return &Scheduler{
+		config:  c,
+		mw:      mw,
+		sem:     make(chan struct{}, c.Concurrency),
+		entries: map[string]*entry{},
+	}, nil
+}
+
+// Run probes all due Routes every second until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		s.schedule(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Results returns the latest cached RequestMetrics of all Routes
+func (s *Scheduler) Results() (results []*kube.RequestMetrics) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results = []*kube.RequestMetrics{}
+	for _, e := range s.entries {
+		results = append(results, e.metrics...)
+	}
+	return
+}
+
+// schedule starts a probe for every due Route and forgets removed Routes
+func (s *Scheduler) schedule(ctx context.Context) {
+	routes := s.mw.List()
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[string]bool{}
+	for _, r := range routes {
+		uid := string(r.GetUID())
+		seen[uid] = true
+		e, ok := s.entries[uid]
+		if !ok {
+			e = &entry{}
+			s.entries[uid] = e
+		}
+		if e.running || now.Before(e.next) {
+			continue
+		}
+		pi, interval := r.Schedule(s.config.Interval, s.config.Timeout)
+		e.running = true
+		e.next = now.Add(interval)
+		go s.probe(ctx, uid, e, r, pi)
+	}
+	for uid := range s.entries {
+		if !seen[uid] {
+			delete(s.entries, uid)
+		}
+	}
+}
+
+// probe checks a single Route and stores the result in e, unless the Route
+// was removed in the meantime
+func (s *Scheduler) probe(ctx context.Context, uid string, e *entry, r *kube.Route, pi *kube.ProbeInfo) {
+	rms := r.Probe(ctx, pi, s.sem)
+	logrus.Debugf("probed %s/%s %s", r.Namespace, r.Name, r.ClusterName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[uid] != e {
+		// route was removed while probing
+		return
+	}
+	e.metrics = rms
+	e.running = false
+}
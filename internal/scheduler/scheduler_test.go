@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/bitsbeats/openshift-route-monitor/internal/kube"
+)
+
+// routeList is a static routeLister
+type routeList []*kube.Route
+
+func (rl routeList) List() []*kube.Route { return rl }
+
+// newRoute creates a Route that is not admitted by any router, so probes
+// finish without any request
+func newRoute(uid string, annotations map[string]string) *kube.Route {
+	return &kube.Route{
+		Route: &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        uid,
+				Namespace:   "test",
+				UID:         types.UID(uid),
+				Annotations: annotations,
+			},
+			Spec: routev1.RouteSpec{Host: uid + ".example.com"},
+		},
+		ClusterName: "test",
+	}
+}
+
+// waitIdle waits until no probe of s is running
+func waitIdle(t *testing.T, s *Scheduler) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.RLock()
+		running := false
+		for _, e := range s.entries {
+			running = running || e.running
+		}
+		s.mu.RUnlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("probes did not finish")
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{}, true},
+		{Config{Interval: time.Minute, Timeout: time.Second, Concurrency: 1}, true},
+		{Config{Interval: -time.Second}, false},
+		{Config{Timeout: -time.Second}, false},
+		{Config{Concurrency: -1}, false},
+	}
+	for _, tt := range tests {
+		s, err := New(tt.config, nil)
+		if (err == nil) != tt.valid {
+			t.Errorf("%+v: got error %v, want valid %t", tt.config, err, tt.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if s.config.Interval <= 0 || s.config.Timeout <= 0 || cap(s.sem) <= 0 {
+			t.Errorf("%+v: got %+v with %d slots, want defaults", tt.config, s.config, cap(s.sem))
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	s, err := New(Config{Interval: time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mw = routeList{
+		newRoute("a", nil),
+		newRoute("b", map[string]string{"thobits.com/ormon-interval": "1h"}),
+		newRoute("skipped", map[string]string{"thobits.com/ormon-skip": "true"}),
+	}
+	ctx := context.Background()
+
+	start := time.Now()
+	s.schedule(ctx)
+	waitIdle(t, s)
+	if results := s.Results(); len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+	intervals := map[string]time.Duration{"a": time.Minute, "b": time.Hour, "skipped": time.Minute}
+	next := map[string]time.Time{}
+	for uid, interval := range intervals {
+		e := s.entries[uid]
+		if e == nil {
+			t.Fatalf("%s: no entry", uid)
+		}
+		if d := e.next.Sub(start); d < interval || d > interval+time.Second {
+			t.Errorf("%s: next probe in %s, want %s", uid, d, interval)
+		}
+		next[uid] = e.next
+	}
+
+	// nothing is due, only a is forced
+	s.entries["a"].next = time.Time{}
+	s.schedule(ctx)
+	waitIdle(t, s)
+	for uid := range intervals {
+		due := uid == "a"
+		if rescheduled := !s.entries[uid].next.Equal(next[uid]); rescheduled != due {
+			t.Errorf("%s: got rescheduled %t, want %t", uid, rescheduled, due)
+		}
+	}
+}
+
+func TestScheduleRunning(t *testing.T) {
+	s, err := New(Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mw = routeList{newRoute("a", nil)}
+	s.entries["a"] = &entry{running: true}
+
+	// a running probe is never started twice, even if it is due
+	s.schedule(context.Background())
+	e := s.entries["a"]
+	if !e.running || !e.next.IsZero() {
+		t.Errorf("got %+v, want the running entry untouched", e)
+	}
+}
+
+func TestSchedulePrune(t *testing.T) {
+	s, err := New(Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mw = routeList{newRoute("a", nil), newRoute("b", nil)}
+	s.schedule(context.Background())
+	waitIdle(t, s)
+
+	s.mw = routeList{newRoute("b", nil)}
+	s.schedule(context.Background())
+	if _, ok := s.entries["a"]; ok {
+		t.Error("removed route a was not pruned")
+	}
+	if _, ok := s.entries["b"]; !ok {
+		t.Error("route b was pruned")
+	}
+	if results := s.Results(); len(results) != 1 || results[0].UID != "b" {
+		t.Errorf("got %+v, want only results of b", results)
+	}
+}
+
+func TestProbeAfterRemoval(t *testing.T) {
+	s, err := New(Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	r := newRoute("a", nil)
+	pi, _ := r.Schedule(s.config.Interval, s.config.Timeout)
+
+	// the route is removed while probing
+	stale := &entry{running: true}
+	s.probe(ctx, "a", stale, r, pi)
+	if len(s.entries) != 0 || len(s.Results()) != 0 {
+		t.Errorf("got entries %+v, want none", s.entries)
+	}
+
+	// the route is removed and added again while probing
+	current := &entry{running: true}
+	s.entries["a"] = current
+	s.probe(ctx, "a", stale, r, pi)
+	if !current.running || current.metrics != nil {
+		t.Errorf("got %+v, want the new entry untouched", current)
+	}
+	s.probe(ctx, "a", current, r, pi)
+	if current.running || len(current.metrics) != 1 {
+		t.Errorf("got %+v, want the result stored", current)
+	}
+}